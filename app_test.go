@@ -0,0 +1,1059 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/praveenprem/timesheet/jira"
+	"github.com/praveenprem/timesheet/jira/jiratest"
+)
+
+const testUser = "dev@example.com"
+
+// newTestApp returns an App reporting on Wednesday 2020-03-04 against a fake Jira holding worklogs of the
+// user and of a colleague across that week and the weeks around it.
+func newTestApp(t *testing.T, format string) (*App, *jiratest.Server, *bytes.Buffer) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	var server = jiratest.NewServer(testUser, "secret")
+	t.Cleanup(server.Close)
+	server.PageSize = 2
+
+	server.AddIssue("DDSP-1", "Jenkins pipeline")
+	server.AddIssue("DDSP-2", "Meetings")
+	server.AddIssue("DDSP-3", "Support")
+	var at = func(day int, hour int) time.Time {
+		return time.Date(2020, 3, day, hour, 0, 0, 0, time.UTC)
+	}
+	server.AddWorklog("DDSP-1", testUser, at(4, 9), 2*3600, "pipeline")
+	server.AddWorklog("DDSP-2", testUser, at(4, 14), 1800, "stand-up")
+	server.AddWorklog("DDSP-2", "colleague@example.com", at(4, 14), 1800, "stand-up")
+	server.AddWorklog("DDSP-1", testUser, at(2, 9), 4*3600, "design")
+	server.AddWorklog("DDSP-3", testUser, at(6, 10), 3600, "on call")
+	server.AddWorklog("DDSP-3", testUser, at(10, 10), 3*3600, "next week")
+	server.AddWorklog("DDSP-3", testUser, at(27, 10), 8*3600, "end of month")
+
+	var out bytes.Buffer
+	var app = &App{
+		Client:      server.Client(),
+		Concurrency: 2,
+		Format:      format,
+		Out:         &out,
+		Started:     "2020-03-04T09:00:00.000+0000",
+	}
+	app.Configuration.Auth = testUser + ":secret"
+	app.Configuration.Location = time.UTC
+	return app, server, &out
+}
+
+func decodeReport(t *testing.T, out *bytes.Buffer) Report {
+	var report Report
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("decoding %q: %v", out.String(), err)
+	}
+	return report
+}
+
+func TestLogTime(t *testing.T) {
+	var app, server, out = newTestApp(t, "json")
+
+	if _, err := LogTime(context.Background(), app.Client, out, "DDSP-2", "Meetings", 5400, app.Started, "retro"); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "1h 30m booked to issue DDSP-2: Meetings\n" {
+		t.Errorf("confirmation = %q", out.String())
+	}
+
+	var logs = server.Worklogs("DDSP-2")
+	var booked = logs[len(logs)-1]
+	if booked.TimeSpentSeconds != 5400 || booked.Started != app.Started || booked.Comment.Text() != "retro" {
+		t.Errorf("booked worklog = %+v", booked)
+	}
+	if booked.Author.EmailAddress != testUser {
+		t.Errorf("booked as %s, want %s", booked.Author.EmailAddress, testUser)
+	}
+
+	if _, err := LogTime(context.Background(), app.Client, out, "NOPE-1", "", 3600, app.Started, ""); exitCode(err) != ExitNotFound {
+		t.Errorf("booking to an unknown issue: got %v, want a not found error", err)
+	}
+}
+
+func TestGetTimeRemaining(t *testing.T) {
+	var app, _, out = newTestApp(t, "json")
+
+	if err := app.GetTimeRemaining(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var report = decodeReport(t, out)
+	if report.Total != 9000 || report.Remaining != 28800-9000 {
+		t.Errorf("booked %d, remaining %d; want 9000 and %d", report.Total, report.Remaining, 28800-9000)
+	}
+
+	out.Reset()
+	app.Format = "table"
+	if err := app.GetTimeRemaining(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); got != "You've 5.50 hours ramaining!\n" {
+		t.Errorf("table output = %q", got)
+	}
+}
+
+func TestReportsUseTheProfileTimezone(t *testing.T) {
+	var app, server, out = newTestApp(t, "json")
+	server.AddWorklog("DDSP-1", testUser, time.Date(2020, 3, 4, 15, 0, 0, 0, time.UTC), 3600, "late")
+	app.Configuration.Location = time.FixedZone("AEST", 10*3600)
+	app.Started = "2020-03-05T09:00:00.000+1000"
+
+	if err := app.GetTimeRemaining(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// The stand-up at 14:00 UTC starts at midnight in UTC+10 and the 15:00 UTC worklog an hour later.
+	var report = decodeReport(t, out)
+	if report.Total != 5400 || len(report.Entries) != 2 {
+		t.Fatalf("booked %d in %d entries, want 5400 in 2", report.Total, len(report.Entries))
+	}
+	for _, entry := range report.Entries {
+		if entry.Date != "2020-03-05" {
+			t.Errorf("entry %q dated %s, want 2020-03-05", entry.Comment, entry.Date)
+		}
+	}
+
+	if !app.isDateBetween("2020-03-04T14:00:00.000+0000", time.Date(2020, 3, 5, 0, 0, 0, 0, time.UTC),
+		time.Date(2020, 3, 5, 0, 0, 0, 0, time.UTC)) {
+		t.Error("a worklog at midnight was left out of its day")
+	}
+}
+
+func TestReportsMatchWorklogsByAccountId(t *testing.T) {
+	var app, server, out = newTestApp(t, "json")
+	server.HideEmails = true
+
+	if err := app.GetHistory(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var report = decodeReport(t, out)
+	if len(report.Entries) != 2 || report.Total != 9000 {
+		t.Errorf("got %d entries totalling %d, want 2 totalling 9000", len(report.Entries), report.Total)
+	}
+	if app.User == nil || app.User.AccountId != jiratest.AccountId(testUser) {
+		t.Errorf("resolved user = %+v", app.User)
+	}
+}
+
+func TestReportsOnServerDeployment(t *testing.T) {
+	var app, server, out = newTestApp(t, "json")
+	server.Deployment = jira.Server
+	server.ContextPath = "/jira"
+	app.Client = server.Client()
+
+	if err := app.GetHistory(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var report = decodeReport(t, out)
+	if len(report.Entries) != 2 || report.Entries[0].Comment != "pipeline" {
+		t.Errorf("entries = %+v", report.Entries)
+	}
+	if app.User == nil || app.User.Name != jiratest.UserName(testUser) {
+		t.Errorf("resolved user = %+v", app.User)
+	}
+}
+
+func TestGetHistory(t *testing.T) {
+	var app, _, out = newTestApp(t, "json")
+
+	if err := app.GetHistory(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var report = decodeReport(t, out)
+	if len(report.Entries) != 2 {
+		t.Fatalf("got %d entries, want 2: %+v", len(report.Entries), report.Entries)
+	}
+	var first = report.Entries[0]
+	if first.Issue != "DDSP-1" || first.Summary != "Jenkins pipeline" || first.Date != "2020-03-04" ||
+		first.Seconds != 7200 || first.Comment != "pipeline" {
+		t.Errorf("first entry = %+v", first)
+	}
+	if report.Entries[1].Issue != "DDSP-2" || report.Entries[1].Comment != "stand-up" {
+		t.Errorf("second entry = %+v", report.Entries[1])
+	}
+}
+
+func TestGetWeekTimesheet(t *testing.T) {
+	var app, _, out = newTestApp(t, "table")
+
+	if err := app.GetWeekTimesheet(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var table = out.String()
+	for _, want := range []string{"| DDSP-1", "| DDSP-2", "| DDSP-3", "Total 7.5h"} {
+		if !strings.Contains(table, want) {
+			t.Errorf("week table is missing %q:\n%s", want, table)
+		}
+	}
+
+	out.Reset()
+	app.Format = "json"
+	if err := app.GetWeekTimesheet(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var report = decodeReport(t, out)
+	if report.Start != "2020-03-02" || report.End != "2020-03-08" || len(report.Entries) != 4 {
+		t.Errorf("week %s..%s with %d entries, want 2020-03-02..2020-03-08 with 4", report.Start, report.End, len(report.Entries))
+	}
+}
+
+func TestReportsFollowTheWorkSchedule(t *testing.T) {
+	var app, server, out = newTestApp(t, "table")
+	schedule, err := newSchedule(6, nil, map[string]float64{"mon": 6, "tue": 6, "wed": 6, "thursday": 6}, "sunday")
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.Configuration.Schedule = &schedule
+	server.AddWorklog("DDSP-3", testUser, time.Date(2020, 3, 1, 10, 0, 0, 0, time.UTC), 2*3600, "on call")
+
+	if err := app.GetTimeRemaining(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "You've 3.50 hours ramaining!\n" {
+		t.Errorf("remaining on a 6h day = %q", out.String())
+	}
+
+	out.Reset()
+	if err := app.GetWeekTimesheet(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var lines = strings.Split(strings.TrimSpace(out.String()), "\n")
+	var header = lines[1]
+	// Friday and Sunday are days off with bookings, Saturday has none.
+	var columns = strings.Fields(strings.Replace(header, "|", " ", -1))
+	if strings.Join(columns, " ") != "Issue Sunday Monday Tuesday Wednesday Thursday Friday" {
+		t.Errorf("week header = %s, want Sunday to Friday", header)
+	}
+	if !strings.Contains(out.String(), "Total 9.5h") {
+		t.Errorf("week table is missing the Sunday booking:\n%s", out.String())
+	}
+	for _, line := range lines[1 : len(lines)-1] {
+		if len(line) != len(header) {
+			t.Errorf("table line %q is %d wide, want %d", line, len(line), len(header))
+		}
+	}
+}
+
+func TestNewSchedule(t *testing.T) {
+	schedule, err := newSchedule(0, nil, nil, "")
+	if err != nil || schedule != defaultSchedule() {
+		t.Errorf("empty schedule = %+v, %v, want the default", schedule, err)
+	}
+
+	schedule, err = newSchedule(7.5, []string{"Monday", "tue", "wed", "thu"}, map[string]float64{"thu": 4}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if schedule.Seconds[time.Monday] != 27000 || schedule.Seconds[time.Thursday] != 14400 || schedule.Seconds[time.Friday] != 0 {
+		t.Errorf("part time schedule = %+v", schedule.Seconds)
+	}
+	if schedule.averageDay() != (3*27000+14400)/4 {
+		t.Errorf("average day = %d", schedule.averageDay())
+	}
+
+	for _, bad := range [][]string{{"funday"}, {"mo"}} {
+		if _, err := newSchedule(8, bad, nil, ""); err == nil {
+			t.Errorf("working days %v were accepted", bad)
+		}
+	}
+	if _, err := newSchedule(8, nil, map[string]float64{"mon": 25}, ""); err == nil {
+		t.Error("a 25 hour day was accepted")
+	}
+}
+
+func TestCalendarsReduceExpectedHours(t *testing.T) {
+	var dir = t.TempDir()
+	var ics = filepath.Join(dir, "holidays.ics")
+	var yaml = filepath.Join(dir, "leave.yaml")
+	writeFile(t, ics, "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nDTSTART;VALUE=DATE:20200305\r\nDTEND;VALUE=DATE:20200307\r\n"+
+		"SUMMARY:Company\r\n  days\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n")
+	writeFile(t, yaml, "# leave\n- date: 2020-03-04\n  hours: 4\n  name: \"Dentist\"\n- from: 2020-03-09\n  to: 2020-03-10\n")
+
+	calendar, err := loadCalendars([]string{ics, yaml})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var app, _, out = newTestApp(t, "json")
+	app.Configuration.Calendar = calendar
+
+	if err := app.GetTimeRemaining(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if report := decodeReport(t, out); report.Expected != 4*3600 || report.Remaining != 5400 {
+		t.Errorf("remaining on a half day: expected %d, remaining %d", report.Expected, report.Remaining)
+	}
+
+	out.Reset()
+	if err := app.GetWeekTimesheet(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var report = decodeReport(t, out)
+	if len(report.Days) != 7 || report.Expected != 20*3600 {
+		t.Fatalf("week of %d days expecting %d seconds, want 7 days and 20h", len(report.Days), report.Expected)
+	}
+	var thursday, friday = report.Days[3], report.Days[4]
+	if thursday.Expected != 0 || thursday.Absence != "Company days" {
+		t.Errorf("holiday = %+v", thursday)
+	}
+	if friday.Booked != 3600 || friday.Expected != 0 || friday.Difference != 3600 {
+		t.Errorf("holiday with a booking = %+v", friday)
+	}
+
+	if _, err := loadCalendars([]string{filepath.Join(dir, "leave.txt")}); err == nil {
+		t.Error("a calendar of unknown type was accepted")
+	}
+}
+
+func TestICalendarTimeZones(t *testing.T) {
+	// New York moved its clocks forward an hour at 2:00 on 8 March 2020. The weekly RRULE counts once.
+	absences, err := parseICalendar(strings.NewReader("BEGIN:VEVENT\r\nDTSTART;TZID=America/New_York:20200308T013000\r\n" +
+		"DTEND;TZID=\"America/New_York\":20200308T033000\r\nRRULE:FREQ=WEEKLY\r\nSUMMARY:Late call\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nDTSTART:20200306T090000Z\r\nDTEND:20200306T110000Z\r\nSUMMARY:Training\r\nEND:VEVENT\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Absence{{"2020-03-08", 3600, "Late call"}, {"2020-03-06", 7200, "Training"}}; fmt.Sprint(absences) != fmt.Sprint(want) {
+		t.Errorf("absences = %v, want %v", absences, want)
+	}
+
+	if _, err := parseICalendar(strings.NewReader("BEGIN:VEVENT\r\nDTSTART;TZID=GMT Standard Time:20200304T090000\r\n" +
+		"DTEND;TZID=GMT Standard Time:20200304T100000\r\nEND:VEVENT\r\n")); err == nil || !strings.Contains(err.Error(), "time zone") {
+		t.Errorf("an unknown time zone gave %v", err)
+	}
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetBalance(t *testing.T) {
+	var app, _, out = newTestApp(t, "json")
+	if err := app.GetBalance(context.Background()); err == nil {
+		t.Error("a balance without a start date was accepted")
+	}
+
+	app.Configuration.BalanceStart = time.Date(2020, 2, 24, 0, 0, 0, 0, time.UTC)
+	app.Configuration.OpeningBalance = 40 * 3600
+	if err := app.GetBalance(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var report = decodeReport(t, out)
+	if len(report.Weeks) != 2 {
+		t.Fatalf("got %d weeks, want 2: %+v", len(report.Weeks), report.Weeks)
+	}
+	if week := report.Weeks[0]; week.Start != "2020-02-24" || week.Delta != -40*3600 || week.Balance != 0 {
+		t.Errorf("first week = %+v", week)
+	}
+	if week := report.Weeks[1]; week.Booked != 6*3600+1800 || week.Expected != 24*3600 || week.Balance != -17*3600-1800 {
+		t.Errorf("current week up to Wednesday = %+v", week)
+	}
+	if report.Balance != -17*3600-1800 || report.ToBook != 32*3600+1800 || report.BookBy != "2020-03-06" {
+		t.Errorf("balance %d, to book %d by %s", report.Balance, report.ToBook, report.BookBy)
+	}
+
+	out.Reset()
+	app.Format = "table"
+	if err := app.GetBalance(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "You're 17.50 hours behind. Book 32.50 hours by 2020-03-06") {
+		t.Errorf("table = %s", out.String())
+	}
+}
+
+func TestPeriodDates(t *testing.T) {
+	var today = time.Date(2026, 8, 19, 0, 0, 0, 0, time.UTC)
+	var tests = []struct {
+		period      string
+		fiscalStart time.Month
+		start, end  string
+	}{
+		{"last-week", time.January, "2026-08-10", "2026-08-16"},
+		{"this-month", time.January, "2026-08-01", "2026-08-31"},
+		{"last-quarter", time.January, "2026-04-01", "2026-06-30"},
+		{"2026-Q1", time.January, "2026-01-01", "2026-03-31"},
+		{"2025-12", time.January, "2025-12-01", "2025-12-31"},
+		{"last-year", time.January, "2025-01-01", "2025-12-31"},
+		{"this-fiscal-year", time.April, "2026-04-01", "2027-03-31"},
+		{"FY2026", time.April, "2025-04-01", "2026-03-31"},
+		{"fy2026", time.January, "2026-01-01", "2026-12-31"},
+	}
+	for _, test := range tests {
+		start, end, err := periodDates(test.period, today, defaultSchedule(), test.fiscalStart)
+		if err != nil {
+			t.Errorf("%s: %v", test.period, err)
+			continue
+		}
+		if start.Format(YmdFormat) != test.start || end.Format(YmdFormat) != test.end {
+			t.Errorf("%s = %s to %s, want %s to %s", test.period, start.Format(YmdFormat), end.Format(YmdFormat),
+				test.start, test.end)
+		}
+	}
+	if _, _, err := periodDates("2026-Q5", today, defaultSchedule(), time.January); exitCode(err) != ExitUsage {
+		t.Errorf("an unknown period gave %v", err)
+	}
+}
+
+func TestGetRange(t *testing.T) {
+	var app, _, out = newTestApp(t, "csv")
+	app.Args = []string{"this-month"}
+	app.Interval, app.GroupBy = "week", "issue"
+
+	if err := app.GetRange(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(out).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want = [][]string{
+		{"period", "start", "end", "issue", "seconds"},
+		{"2020-03-02", "2020-03-02", "2020-03-08", "DDSP-1", "21600"},
+		{"2020-03-02", "2020-03-02", "2020-03-08", "DDSP-2", "1800"},
+		{"2020-03-02", "2020-03-02", "2020-03-08", "DDSP-3", "3600"},
+		{"2020-03-09", "2020-03-09", "2020-03-15", "DDSP-3", "10800"},
+		{"2020-03-23", "2020-03-23", "2020-03-29", "DDSP-3", "28800"},
+	}
+	if fmt.Sprint(rows) != fmt.Sprint(want) {
+		t.Errorf("rows = %v\nwant %v", rows, want)
+	}
+
+	out.Reset()
+	app.Args, app.From, app.To = nil, "2020-03-01", "2020-03-31"
+	app.Interval, app.GroupBy, app.Format = "month", "project", "table"
+	if err := app.GetRange(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "2020-03      DDSP                  18.50") {
+		t.Errorf("table = %s", out.String())
+	}
+}
+
+func TestReportsGroupBy(t *testing.T) {
+	var app, server, out = newTestApp(t, "table")
+	var epic = &jira.Parent{Key: "EPIC-1"}
+	epic.Fields.Summary = "Delivery pipeline"
+	server.SetFields("DDSP-1", jira.IssueFields{Parent: epic, Components: []jira.Component{{Name: "Continuous integration"}, {Name: "Backend"}}})
+	server.SetFields("DDSP-2", jira.IssueFields{Components: []jira.Component{{Name: "Admin"}}})
+
+	app.GroupBy = "component"
+	if err := app.GetWeekTimesheet(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range []string{
+		"| Component                       | Monday     |",
+		"| (none)                          |            |            |            |            | 1.0        | 1.0        |",
+		"| Admin                           |            |            | 0.5        |            |            | 0.5        |",
+		"| Backend, Continuous integration | 4.0        |            | 2.0        |            |            | 6.0        |",
+	} {
+		if !strings.Contains(out.String(), row) {
+			t.Errorf("week table has no row %q:\n%s", row, out.String())
+		}
+	}
+
+	out.Reset()
+	app.GroupBy = "project"
+	if err := app.GetMonthTimesheet(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "|  DDSP      | 4.0        |            | 2.5        |            | 1.0        | 7.5         |") {
+		t.Errorf("month table has no sub-total of the first week:\n%s", out.String())
+	}
+
+	out.Reset()
+	app.GroupBy = "epic"
+	if err := app.GetMonthTimesheet(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "|  EPIC-1 Delivery pipeline | 4.0        |") {
+		t.Errorf("month table has no row of the epic by name:\n%s", out.String())
+	}
+
+	out.Reset()
+	app.Format, app.GroupBy = "csv", "project"
+	if err := app.GetWeekTimesheet(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(out).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(rows) != "[[period start end project seconds] [2020-03-02 2020-03-02 2020-03-08 DDSP 27000]]" {
+		t.Errorf("week csv by project = %v", rows)
+	}
+
+	out.Reset()
+	app.Format, app.GroupBy = "json", "type"
+	if err := app.GetMonthTimesheet(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var report = decodeReport(t, out)
+	if report.GroupBy != "type" || report.Interval != "week" || len(report.Rows) != 3 || report.Rows[0].Start != "2020-03-02" ||
+		report.Rows[0].Group != "(none)" || report.Rows[0].Seconds != 27000 || report.Rows[2].Seconds != 28800 {
+		t.Errorf("month rows by type = %+v", report.Rows)
+	}
+
+	out.Reset()
+	app.GroupBy, app.Args, app.Interval = "epic", []string{"this-month"}, "month"
+	if err := app.GetRange(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	report = decodeReport(t, out)
+	if len(report.Rows) != 2 || report.Rows[0].Group != "(none)" || report.Rows[0].Seconds != 45000 ||
+		report.Rows[1].Group != "EPIC-1 Delivery pipeline" || report.Rows[1].Seconds != 21600 {
+		t.Errorf("rows by epic = %+v", report.Rows)
+	}
+}
+
+func TestGetMonthTimesheet(t *testing.T) {
+	var app, _, out = newTestApp(t, "csv")
+
+	if err := app.GetMonthTimesheet(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := csv.NewReader(out).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 7 {
+		t.Fatalf("got %d rows, want a header and 6 worklogs: %v", len(rows), rows)
+	}
+	if strings.Join(rows[0], ",") != "issue,summary,date,seconds,comment" {
+		t.Errorf("header = %v", rows[0])
+	}
+	if last := rows[len(rows)-1]; last[0] != "DDSP-3" || last[2] != "2020-03-27" || last[3] != "28800" {
+		t.Errorf("last row = %v", last)
+	}
+
+	out.Reset()
+	app.Format = "table"
+	if err := app.GetMonthTimesheet(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "Total(h) | 18.5") {
+		t.Errorf("month table is missing the total:\n%s", out.String())
+	}
+}
+
+func TestOfflineQueueAndSync(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	var app, server, _ = newTestApp(t, "table")
+	var ctx = context.Background()
+
+	var online = app.Client
+	var offline = server.Client()
+	offline.BaseURL = "http://127.0.0.1:1"
+	app.Client = offline
+	for _, comment := range []string{"retro", "planning"} {
+		booked, err := app.bookTime(ctx, "DDSP-2", 5400, app.Started, comment)
+		if err != nil || booked {
+			t.Fatalf("booking while offline: booked %v, %v", booked, err)
+		}
+	}
+
+	journal, err := readJournal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(journal.Entries) != 2 || journal.Entries[0].Comment != "retro" || journal.Entries[1].Id != 2 {
+		t.Fatalf("queued %+v", journal.Entries)
+	}
+
+	// The first booking reached Jira before an earlier sync was interrupted.
+	server.AddWorklog("DDSP-2", testUser, time.Date(2020, 3, 4, 9, 0, 0, 0, time.UTC), 5400, "retro")
+	var before = len(server.Worklogs("DDSP-2"))
+
+	if err := app.SyncQueue(ctx); exitCode(err) != ExitTransport {
+		t.Fatalf("sync while offline: got %v, want a transport error", err)
+	}
+	app.Client = online
+	if err := app.SyncQueue(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var logs = server.Worklogs("DDSP-2")
+	if len(logs) != before+1 || logs[len(logs)-1].Comment.Text() != "planning" {
+		t.Errorf("worklogs after sync = %+v", logs)
+	}
+	if journal, err = readJournal(); err != nil || len(journal.Entries) != 0 {
+		t.Errorf("queue after sync = %+v, %v", journal, err)
+	}
+
+	// A booking which can't be queued either reports both failures.
+	var blocked = filepath.Join(t.TempDir(), "file")
+	writeFile(t, blocked, "")
+	t.Setenv("XDG_STATE_HOME", blocked)
+	app.Client = offline
+	booked, err := app.bookTime(ctx, "DDSP-2", 5400, app.Started, "demo")
+	if booked || exitCode(err) != ExitTransport || !strings.Contains(fmt.Sprint(err), "queueing failed") {
+		t.Errorf("booking without a queue: booked %v, %v", booked, err)
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	for _, c := range []struct {
+		input string
+		want  Duration
+	}{
+		{"1h 30m", 5400},
+		{"1h30m", 5400},
+		{"1.5h", 5400},
+		{"90m", 5400},
+		{"90", 5400},
+		{"1:30", 5400},
+		{"0:45", 2700},
+		{" 2H ", 7200},
+		{".5h", 1800},
+		{"2d", 57600},
+		{"1w", 144000},
+		{"1w 2d 3h 4m", 144000 + 57600 + 10800 + 240},
+	} {
+		if got, err := ParseDuration(c.input, defaultSchedule()); err != nil || got != c.want {
+			t.Errorf("ParseDuration(%q) = %d, %v, want %d", c.input, got, err, c.want)
+		}
+	}
+	for _, input := range []string{"", "8x", "soon", "1h x", "h", "1:60", "1:5", "-1h", "30s", "0m", "0.4m",
+		"1.5.5h", "2h2h", "1m1h", "1h 30", "30 1h", "1d 1w"} {
+		if got, err := ParseDuration(input, defaultSchedule()); err == nil {
+			t.Errorf("ParseDuration(%q) = %d, want an error", input, got)
+		}
+	}
+
+	// Days and weeks follow the schedule of the profile.
+	schedule, err := newSchedule(7.5, []string{"mon", "tue", "wed", "thu"}, nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ParseDuration("1w 1d", schedule); err != nil || got != 5*27000 {
+		t.Errorf("ParseDuration(1w 1d) on four 7.5 hour days = %d, %v, want %d", got, err, 5*27000)
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	var app App
+	app.Configuration.Location = time.UTC
+	app.Configuration.Calendar = Calendar{"2026-10-09": {{Date: "2026-10-09", Name: "Holiday"}}}
+	var now = time.Date(2026, 10, 14, 15, 0, 0, 0, time.UTC) // a Wednesday
+
+	for _, c := range []struct {
+		expr string
+		want string
+	}{
+		{"", "2026-10-14"},
+		{"today", "2026-10-14"},
+		{"yesterday", "2026-10-13"},
+		{"Tomorrow", "2026-10-15"},
+		{"mon", "2026-10-12"},
+		{"friday", "2026-10-16"},
+		{"sun", "2026-10-18"},
+		{"last friday", "2026-10-09"},
+		{"last  Mon", "2026-10-05"},
+		{"-1", "2026-10-13"},
+		{"+2", "2026-10-16"},
+		{"-3d", "2026-10-11"},
+		{"-1w", "2026-10-07"},
+		{"-2wd", "2026-10-12"},
+		{"-3wd", "2026-10-08"},
+		{"+3wd", "2026-10-19"},
+		{"2026-10-01", "2026-10-01"},
+		{"2026-10-14 13:30", "2026-10-14 13:30"},
+	} {
+		date, hasTime, err := app.parseDate(c.expr, now)
+		var got = date.Format(YmdFormat)
+		if hasTime {
+			got = date.Format(YmdHmFormat)
+		}
+		if err != nil || got != c.want {
+			t.Errorf("parseDate(%q) = %s, %v, want %s", c.expr, got, err, c.want)
+		}
+	}
+	for _, expr := range []string{"someday", "last", "last week", "2026-13-01", "-1x", "1", "|3", "2026-10-14 25:00"} {
+		if date, _, err := app.parseDate(expr, now); err == nil {
+			t.Errorf("parseDate(%q) = %s, want an error", expr, date)
+		}
+	}
+
+	app.Configuration.Schedule = &Schedule{WeekStart: time.Sunday}
+	if date, _, err := app.parseDate("sun", now); err != nil || date.Format(YmdFormat) != "2026-10-11" {
+		t.Errorf("sun of a week starting on Sunday = %s, %v", date.Format(YmdFormat), err)
+	}
+	if _, _, err := app.parseDate("-1wd", now); err == nil {
+		t.Error("a working day was found in a schedule without any")
+	}
+}
+
+func TestResolveDate(t *testing.T) {
+	for _, c := range []struct {
+		date, at string
+		want     string
+	}{
+		{"2026-10-14", "", "2026-10-14T09:00:00.000+0000"},
+		{"2026-10-14", "13:30", "2026-10-14T13:30:00.000+0000"},
+		{"2026-10-14 8:05", "", "2026-10-14T08:05:00.000+0000"},
+	} {
+		var app = App{Date: c.date, At: c.at}
+		app.Configuration.Location = time.UTC
+		if err := app.resolveDate(); err != nil || app.Started != c.want {
+			t.Errorf("-d %q -at %q started %s, %v, want %s", c.date, c.at, app.Started, err, c.want)
+		}
+	}
+	for _, c := range [][2]string{{"2026-10-14 13:30", "14:00"}, {"", "25:00"}, {"", "1pm"}, {"soon", ""}} {
+		var app = App{Date: c[0], At: c[1]}
+		if err := app.resolveDate(); exitCode(err) != ExitUsage {
+			t.Errorf("-d %q -at %q = %v, want a usage error", c[0], c[1], err)
+		}
+	}
+
+	var app App
+	app.Configuration.Location = time.UTC
+	if err := app.parse([]string{"log", "-r", "DDSP-4", "-t", "1h", "-d", "yesterday", "-at", "8:15"}); err != nil {
+		t.Fatal(err)
+	}
+	var yesterday = time.Now().In(time.UTC).AddDate(0, 0, -1).Format(YmdFormat)
+	if app.Started != yesterday+"T08:15:00.000+0000" {
+		t.Errorf("started %s, want yesterday at 8:15", app.Started)
+	}
+}
+
+func TestReportsWriteJiraDurations(t *testing.T) {
+	var app, _, out = newTestApp(t, "table")
+	app.Configuration.DurationFormat = DurationJira
+	if err := app.GetTimeRemaining(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "You've 5h 30m ramaining!") {
+		t.Errorf("remaining = %s", out.String())
+	}
+}
+
+func TestDurationText(t *testing.T) {
+	for _, c := range []struct {
+		seconds Duration
+		style   string
+		verb    string
+		want    string
+	}{
+		{27000, DurationJira, "%.1f", "7h 30m"},
+		{27000, DurationDecimal, "%.1f", "7.5"},
+		{27000, "", "%.2fh", "7.50h"},
+		{3600, DurationJira, "%.1f", "1h"},
+		{1800, DurationJira, "%.1f", "30m"},
+		{0, DurationJira, "%.1f", "0m"},
+		{-5400, DurationJira, "%+.1f", "-1h 30m"},
+		{5400, DurationJira, "%+.1f", "+1h 30m"},
+		{5400, DurationDecimal, "%+.1f", "+1.5"},
+		{150000, DurationJira, "%.1f", "41h 40m"},
+	} {
+		if got := c.seconds.Text(c.style, c.verb); got != c.want {
+			t.Errorf("Duration(%d).Text(%q, %q) = %q, want %q", c.seconds, c.style, c.verb, got, c.want)
+		}
+	}
+}
+
+func TestReportsUseTheWorklogCache(t *testing.T) {
+	var app, server, out = newTestApp(t, "json")
+	var ctx = context.Background()
+	if err := app.GetWeekTimesheet(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// Changes made in Jira after the cache was filled come from the change feeds. Those to the worklogs of
+	// others on issues the user never booked on aren't fetched.
+	server.AddWorklog("DDSP-3", testUser, time.Date(2020, 3, 5, 10, 0, 0, 0, time.UTC), 1800, "late")
+	if err := app.Client.DeleteWorklog(ctx, "DDSP-2", server.Worklogs("DDSP-2")[0].Id, jira.EstimateAdjustment{}); err != nil {
+		t.Fatal(err)
+	}
+	server.AddIssue("DDSP-9", "Other team")
+	server.AddWorklog("DDSP-9", "colleague@example.com", time.Date(2020, 3, 5, 9, 0, 0, 0, time.UTC), 3600, "theirs")
+	server.SetFields("DDSP-1", jira.IssueFields{Summary: "Jenkins pipeline rewrite"})
+	var design = server.Worklogs("DDSP-1")[1].Id
+	if _, err := app.Client.UpdateWorklog(ctx, "DDSP-1", design, &jira.TimeLog{Comment: jira.NewComment("pipeline design")},
+		jira.EstimateAdjustment{}); err != nil {
+		t.Fatal(err)
+	}
+	var searches = len(server.Requests())
+
+	out.Reset()
+	if err := app.GetWeekTimesheet(ctx); err != nil {
+		t.Fatal(err)
+	}
+	var report = decodeReport(t, out)
+	if report.Total != 7*3600+1800 || len(report.Entries) != 4 || report.Entries[2].Comment != "late" {
+		t.Errorf("refreshed week = %+v", report)
+	}
+	// The issue of the changed worklog is looked up again, the issues of others are left alone.
+	if entry := report.Entries[0]; entry.Comment != "pipeline design" || entry.Summary != "Jenkins pipeline rewrite" {
+		t.Errorf("the changed worklog reads %q on %q", entry.Comment, entry.Summary)
+	}
+	for _, request := range server.Requests()[searches:] {
+		if strings.Contains(request, "worklogDate") || strings.Contains(request, "DDSP-9") {
+			t.Errorf("refreshing a covered week requested %s", request)
+		}
+	}
+
+	// -offline renders from the cache alone.
+	var offline = server.Client()
+	offline.BaseURL = "http://127.0.0.1:1"
+	app.Client, app.User, app.Offline = offline, nil, true
+	out.Reset()
+	if err := app.GetWeekTimesheet(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if offlineReport := decodeReport(t, out); offlineReport.Total != report.Total {
+		t.Errorf("offline week total %d, want %d", offlineReport.Total, report.Total)
+	}
+}
+
+func TestLegacyArgs(t *testing.T) {
+	for args, want := range map[string]string{
+		"-week -d -1":             "[week -d -1]",
+		"-r DDSP-4 -t 1h":         "[log -r DDSP-4 -t 1h]",
+		"-edit 10042 -t 2h":       "[edit 10042 -t 2h]",
+		"-delete=10042 -yes":      "[delete 10042 -yes]",
+		"-login":                  "[config login]",
+		"-h":                      "[help]",
+		"week -format json -d -1": "[week -format json -d -1]",
+		"-r X -t 1h -m -week":     "[log -r X -t 1h -m -week]",
+		"-m=-month -week":         "[week -m=-month]",
+	} {
+		got, err := legacyArgs(strings.Fields(args))
+		if err != nil || fmt.Sprint(got) != want {
+			t.Errorf("legacyArgs(%s) = %v, %v, want %s", args, got, err, want)
+		}
+	}
+	if _, err := legacyArgs([]string{"-week", "-month"}); err == nil {
+		t.Error("legacyArgs accepted -week with -month")
+	}
+}
+
+func TestParseCommands(t *testing.T) {
+	var app App
+	app.Configuration.Location = time.UTC
+	if err := app.parse([]string{"range", "-group-by", "project", "last-quarter", "-interval", "week"}); err != nil {
+		t.Fatal(err)
+	}
+	if app.Command != "range" || fmt.Sprint(app.Args) != "[last-quarter]" || app.GroupBy != "project" || app.Interval != "week" {
+		t.Errorf("parsed range = %q %v %q %q", app.Command, app.Args, app.GroupBy, app.Interval)
+	}
+
+	app = App{}
+	if err := app.parse([]string{"edit", "10042", "-r", "DDSP-4", "-t", "2h"}); err != nil || app.EditId != "10042" || app.Spent != 7200 {
+		t.Errorf("parsed edit = %q %v, %v", app.EditId, app.Spent, err)
+	}
+
+	app = App{}
+	if err := app.parse([]string{"week", "-h"}); !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("parse(week -h) = %v, want flag.ErrHelp", err)
+	}
+
+	for _, args := range [][]string{{"bogus"}, {"week", "extra"}, {"edit", "-t", "2h"}, {"week", "-format", "xml"}, {"log", "-r", "DDSP-4", "-t", "8x"}} {
+		app = App{}
+		var err = app.parse(args)
+		if exitCode(err) != ExitUsage {
+			t.Errorf("parse(%v) = %v, want a usage error", args, err)
+		}
+	}
+}
+
+func TestComplete(t *testing.T) {
+	var app, _, _ = newTestApp(t, "table")
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	t.Setenv("TIMESHEET_CONFIG", filepath.Join(t.TempDir(), "config.json"))
+	var ctx = context.Background()
+	if err := app.refreshAssigned(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.bookTime(ctx, "DDSP-3", 3600, app.Started, "support"); err != nil {
+		t.Fatal(err)
+	}
+
+	for words, want := range map[string]string{
+		"we":                     "[week\tPrint the timesheet of the week]",
+		"week -form":             "[-format\tOutput format. One of: table, json, csv, tsv]",
+		"week -format c":         "[csv]",
+		"range last-q":           "[last-quarter]",
+		"week -d -1 -offline -g": "[-group-by\tWhat the time is summed by, with sub-totals. One of: issue, project, epic, component, label, type]",
+		"__complete w":           "[]",
+		"-week -d -1 -form":      "[-format\tOutput format. One of: table, json, csv, tsv]",
+	} {
+		if got := complete(strings.Fields(words)); fmt.Sprint(got) != want {
+			t.Errorf("complete(%s) = %q, want %q", words, got, want)
+		}
+	}
+
+	// Booked tickets come first, then the assigned issues.
+	var want = "[DDSP-3\tSupport DDSP-1\tJenkins pipeline DDSP-2\tMeetings]"
+	for _, words := range [][]string{{"log", "-r", "dd"}, {"-r", "dd"}, {"-t", "1h", "-r", ""}, {"start", ""}} {
+		if got := complete(words); fmt.Sprint(got) != want {
+			t.Errorf("complete(%q) = %q, want %q", words, got, want)
+		}
+	}
+}
+
+func TestBookTimeChecksTheTicket(t *testing.T) {
+	var app, server, _ = newTestApp(t, "table")
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	var ctx = context.Background()
+	if _, err := app.bookTime(ctx, "DDSP-3", 3600, app.Started, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := app.bookTime(ctx, "DSDP-3", 3600, app.Started, "")
+	if exitCode(err) != ExitNotFound || !strings.Contains(err.Error(), "did you mean DDSP-3 (Support)?") {
+		t.Errorf("booking a typo: got %v, want a suggestion of DDSP-3", err)
+	}
+
+	server.SetFields("DDSP-1", jira.IssueFields{Status: &jira.Status{Name: "Done", StatusCategory: jira.StatusCategory{Key: "done"}}})
+	var before = len(server.Worklogs("DDSP-1"))
+	if _, err := app.bookTime(ctx, "DDSP-1", 3600, app.Started, ""); exitCode(err) != ExitRejected || !strings.Contains(err.Error(), "is Done") {
+		t.Errorf("booking a done issue: got %v, want a refusal", err)
+	}
+	app.Configuration.ClosedStatuses = []string{"Closed"}
+	if _, err := app.bookTime(ctx, "DDSP-1", 3600, app.Started, ""); err != nil {
+		t.Errorf("booking an issue done but not closed: %v", err)
+	}
+	app.Configuration.Projects = []string{"OPS"}
+	if _, err := app.bookTime(ctx, "DDSP-1", 3600, app.Started, ""); err == nil || !strings.Contains(err.Error(), "project DDSP") {
+		t.Errorf("booking outside the projects: got %v, want a refusal", err)
+	}
+	if after := len(server.Worklogs("DDSP-1")); after != before+1 {
+		t.Errorf("%d worklogs booked on DDSP-1, want 1", after-before)
+	}
+}
+
+func TestListWorklogs(t *testing.T) {
+	var app, server, out = newTestApp(t, "table")
+	if err := app.ListWorklogs(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"DDSP-1", "DDSP-2"} {
+		for _, log := range server.Worklogs(key) {
+			var listed = strings.Contains(out.String(), log.Id+" ")
+			var own = log.Author.EmailAddress == testUser && strings.HasPrefix(log.Started, "2020-03-04")
+			if listed != own {
+				t.Errorf("worklog %s of %s on %s listed: %v, want %v", log.Id, log.Author.EmailAddress, log.Started, listed, own)
+			}
+		}
+	}
+}
+
+// worklogOf returns the ID of the first worklog of the author on the issue.
+func worklogOf(t *testing.T, server *jiratest.Server, key string, author string) string {
+	for _, log := range server.Worklogs(key) {
+		if log.Author.EmailAddress == author {
+			return log.Id
+		}
+	}
+	t.Fatalf("%s has no worklog on %s", author, key)
+	return ""
+}
+
+func TestEditWorklog(t *testing.T) {
+	var app, server, _ = newTestApp(t, "table")
+	var ctx = context.Background()
+	var id = worklogOf(t, server, "DDSP-1", testUser)
+	if err := app.parse([]string{"edit", id, "-r", "DDSP-1", "-t", "1h", "-m", "pairing", "-estimate", "new", "-new-estimate", "2h"}); err != nil {
+		t.Fatal(err)
+	}
+	app.In = strings.NewReader("y\n")
+	if err := app.EditWorklog(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var updated = server.Worklogs("DDSP-1")[0]
+	if updated.Id != id || updated.TimeSpentSeconds != 3600 || updated.Comment.Text() != "pairing" {
+		t.Errorf("updated worklog = %+v", updated)
+	}
+	var requests = server.Requests()
+	if want := "PUT /rest/api/3/issue/DDSP-1/worklog/" + id + "?adjustEstimate=new&newEstimate=2h"; requests[len(requests)-1] != want {
+		t.Errorf("last request = %q, want %q", requests[len(requests)-1], want)
+	}
+}
+
+func TestDeleteWorklog(t *testing.T) {
+	var app, server, out = newTestApp(t, "table")
+	var ctx = context.Background()
+	app.Ticket, app.DeleteId = "DDSP-2", worklogOf(t, server, "DDSP-2", testUser)
+
+	app.In = strings.NewReader("n\n")
+	if err := app.DeleteWorklog(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(server.Worklogs("DDSP-2")) != 2 || !strings.HasSuffix(out.String(), "Aborted\n") {
+		t.Errorf("declining deleted the worklog or wrote %q", out.String())
+	}
+
+	app.In = strings.NewReader("y\n")
+	if err := app.DeleteWorklog(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if logs := server.Worklogs("DDSP-2"); len(logs) != 1 || logs[0].Author.EmailAddress == testUser {
+		t.Errorf("worklogs left after delete = %+v", logs)
+	}
+}
+
+func TestChangingWorklogsOfOthers(t *testing.T) {
+	var app, server, _ = newTestApp(t, "table")
+	var ctx = context.Background()
+	app.Yes = true
+	var before = len(server.Requests())
+
+	app.Ticket, app.EditId = "DDSP-2", "99999"
+	if err := app.EditWorklog(ctx); exitCode(err) != ExitNotFound {
+		t.Errorf("editing a missing worklog = %v, want a not found error", err)
+	}
+	var ticketErr *TicketError
+	app.DeleteId = worklogOf(t, server, "DDSP-2", "colleague@example.com")
+	if err := app.DeleteWorklog(ctx); !errors.As(err, &ticketErr) {
+		t.Errorf("deleting the worklog of a colleague = %v, want it refused", err)
+	}
+	app.EditId = app.DeleteId
+	if err := app.EditWorklog(ctx); !errors.As(err, &ticketErr) {
+		t.Errorf("editing the worklog of a colleague = %v, want it refused", err)
+	}
+	for _, request := range server.Requests()[before:] {
+		if !strings.HasPrefix(request, "GET ") {
+			t.Errorf("refused change sent %s", request)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	for _, c := range []struct {
+		a, b string
+		want int
+	}{
+		{"DDSP-4321", "DDSP-4321", 0},
+		{"DDSP-4312", "DDSP-4321", 1},
+		{"DDSP-432", "DDSP-4321", 1},
+		{"DSP-4322", "DDSP-4321", 2},
+		{"OPS-1", "DDSP-4321", 6},
+	} {
+		if got := editDistance(c.a, c.b); got != c.want {
+			t.Errorf("editDistance(%s, %s) = %d, want %d", c.a, c.b, got, c.want)
+		}
+	}
+}
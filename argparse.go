@@ -4,7 +4,13 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/praveenprem/timesheet/jira"
 )
 
 /**
@@ -14,97 +20,424 @@ import (
  * Created on: 29/02/2020 17:52
  */
 
-func (app *App) Parser() {
-	app.Started = app.getDateTime()
-
-	flag.BoolVar(&app.Help, "h", false,
-		"HELP: This tool can be used to log time spent on a specific Jira ticket on a project.")
-	flag.StringVar(&app.Ticket, "r", "",
-		"REQUIRED: Jira ticket reference. E.g. DDSP-4")
-	flag.StringVar(&app.TimeSpent, "t", "",
-		"REQUIRED: The time spent as days (#d), hours (#h), or minutes (#m or #). E.g. 8h")
-	flag.StringVar(&app.Started, "d", "",
-		fmt.Sprintf("Default %s. The date on which the worklog effort was started in full date (YYYY-MM-DD) or"+
-			" relative date (-N) format. eg: 2006-01-02 or -1.", app.getDate()))
-	flag.StringVar(&app.Comment, "m", "",
-		"OPTIONAL: A comment about the worklog")
-	flag.StringVar(&app.Encode, "e", "",
-		"HELP: Base64 encode the given credentials."+
-			" Format: email:token;domain. e.g. example@example.com:abcThisIsFake;xyz.atlassian.net")
-	flag.BoolVar(&app.TimeRemaining, "remaining", false,
-		"HELP: Print how many hour can be book for the current day. -d is also available")
-	flag.BoolVar(&app.History, "history", false,
-		"HELP: Print the timesheet of the day -d is also available to change the week")
-	flag.BoolVar(&app.PrintWeek, "week", false,
-		"HELP: Print timesheet of the current week. -d is also available to change the week")
-	flag.BoolVar(&app.PrintMonth, "month", false,
-		"HELP: Print timesheet of the current month. -d is also available to change the week")
-	flag.BoolVar(&app.Version, "v", false, "Print application version")
-	flag.Parse()
-	app.validate()
-}
-
-func (app *App) validate() {
-
-	if len(os.Args[1:]) < 1 {
-		fmt.Printf("no arguments are given\n\n")
-		app.usage()
-		os.Exit(1)
-	}
-
-	if app.Started != "" {
-		if len(RelativeDateFormat.FindStringSubmatch(app.Started)) > 0 {
-			relativeTime, err := app.GetDateFromRelative()
-			if err != nil {
-				panic(err)
+// Parser reads the command line: the command, its arguments and its own flags.
+func (app *App) Parser() error {
+	return app.parse(os.Args[1:])
+}
+
+// parse finds the command named by the first argument and parses the rest with the flags of that command.
+// The flat command line of older versions is translated to its command first. It returns flag.ErrHelp once
+// -h has printed the help of the command.
+func (app *App) parse(args []string) error {
+	if len(args) == 0 {
+		printUsage(os.Stderr)
+		return usageErrorf("no command given")
+	}
+	args, err := legacyArgs(args)
+	if err != nil {
+		return err
+	}
+
+	var command = findCommand(args[0])
+	if command == nil {
+		return usageErrorf("unknown command %q. see timesheet help", args[0])
+	}
+	app.Command = command.Name
+	app.flags = flag.NewFlagSet("timesheet "+command.Name, flag.ContinueOnError)
+	app.flags.Usage = func() { command.help(app.flags.Output(), app.flags) }
+	if command.Flags != nil {
+		command.Flags(app, app.flags)
+	}
+
+	if command.RawArgs {
+		app.Args = args[1:]
+	} else if app.Args, err = parseFlags(app.flags, args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return &UsageError{Msg: err.Error()}
+	}
+	if err := app.resolveDate(); err != nil {
+		return err
+	}
+	if command.Validate != nil {
+		return command.Validate(app)
+	}
+	if len(app.Args) > 0 {
+		return usageErrorf("%s doesn't take any arguments", command.Name)
+	}
+	return nil
+}
+
+// valueFlags returns the names of the flags of the commands which take a value.
+func valueFlags() map[string]bool {
+	var names = make(map[string]bool)
+	for _, command := range commands {
+		if command.Flags == nil {
+			continue
+		}
+		var flags = flag.NewFlagSet(command.Name, flag.ContinueOnError)
+		command.Flags(&App{}, flags)
+		flags.VisitAll(func(f *flag.Flag) {
+			names[f.Name] = names[f.Name] || !isBoolFlag(f)
+		})
+	}
+	return names
+}
+
+// parseFlags parses the flags given before, between and after the positional arguments, which it returns.
+func parseFlags(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		args = flags.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional, args = append(positional, args[0]), args[1:]
+	}
+}
+
+// legacyModes maps the mode flags of the flat command line to their commands. Modes taking a value pass it
+// on as the last argument of the command.
+var legacyModes = map[string]struct {
+	command []string
+	valued  bool
+}{
+	"remaining": {command: []string{"remaining"}},
+	"history":   {command: []string{"day"}},
+	"week":      {command: []string{"week"}},
+	"month":     {command: []string{"month"}},
+	"list":      {command: []string{"list"}},
+	"login":     {command: []string{"config", "login"}},
+	"v":         {command: []string{"version"}},
+	"edit":      {command: []string{"edit"}, valued: true},
+	"delete":    {command: []string{"delete"}, valued: true},
+	"e":         {command: []string{"config", "encode"}, valued: true},
+}
+
+// legacyArgs translates the flat command line of older versions, e.g. timesheet -week -d -1, to a command.
+// Without a mode flag the flags book time with log. Giving more than one mode is an error rather than one of
+// them being ignored. The values of other flags are passed on as they are, so -m -week is a comment.
+func legacyArgs(args []string) ([]string, error) {
+	if !strings.HasPrefix(args[0], "-") {
+		return args, nil
+	}
+	if len(args) == 1 && (args[0] == "-h" || args[0] == "-help" || args[0] == "--help") {
+		return []string{"help"}, nil
+	}
+
+	var command, modes, rest []string
+	var valued = valueFlags()
+	for i := 0; i < len(args); i++ {
+		var name = strings.TrimLeft(args[i], "-")
+		var value string
+		var hasValue bool
+		if at := strings.Index(name, "="); at >= 0 {
+			name, value, hasValue = name[:at], name[at+1:], true
+		}
+		mode, found := legacyModes[name]
+		if !strings.HasPrefix(args[i], "-") || !found {
+			rest = append(rest, args[i])
+			if strings.HasPrefix(args[i], "-") && !hasValue && valued[name] && i+1 < len(args) {
+				i++
+				rest = append(rest, args[i])
 			}
-			app.Started = fmt.Sprintf("%sT%s", relativeTime.Format(YmdFormat), app.getTimeFixed())
-		} else if DateFormat.MatchString(app.Started) {
-			app.Started = fmt.Sprintf("%sT%s", app.Started, app.getTimeFixed())
-		} else {
-			panic("provided date didn't match expected format. try -h for help")
+			continue
+		}
+		modes = append(modes, "-"+name)
+		command = append([]string(nil), mode.command...)
+		if mode.valued {
+			if !hasValue {
+				if i+1 == len(args) {
+					return nil, usageErrorf("flag needs an argument: -%s", name)
+				}
+				i++
+				value = args[i]
+			}
+			command = append(command, value)
 		}
-	} else {
-		app.Started = app.getDateTime()
 	}
 
-	if app.Help {
-		app.usage()
-		os.Exit(0)
+	switch len(modes) {
+	case 0:
+		return append([]string{"log"}, args...), nil
+	case 1:
+		return append(command, rest...), nil
+	default:
+		return nil, usageErrorf("%s can't be used together", strings.Join(modes, " and "))
+	}
+}
+
+// resolveDate turns the date expression of -d and the time of day of -at into the start time of the worklog,
+// now when neither is given. Days without a time start at 9 AM in the profile's timezone.
+func (app *App) resolveDate() error {
+	if app.Date == "" && app.At == "" {
+		app.Started = app.getDateTime()
+		return nil
 	}
+	date, hasTime, err := app.parseDate(app.Date, time.Now())
+	if err != nil {
+		return &UsageError{Msg: "-d: " + err.Error()}
+	}
+	if app.At != "" {
+		if hasTime {
+			return usageErrorf("-d already gives the time the worklog started, -at can't be used with it")
+		}
+		hour, minute, err := parseClock(app.At)
+		if err != nil {
+			return &UsageError{Msg: "-at: " + err.Error()}
+		}
+		date = time.Date(date.Year(), date.Month(), date.Day(), hour, minute, 0, 0, app.location())
+	} else if !hasTime {
+		date = time.Date(date.Year(), date.Month(), date.Day(), defaultStartHour, 0, 0, 0, app.location())
+	}
+	app.Started = jiraTimestamp(date)
+	return nil
+}
+
+// isStartSet tells whether the command line gives the start of the worklog, with -d or -at.
+func (app *App) isStartSet() bool {
+	return app.isFlagSet("d") || app.isFlagSet("at")
+}
 
-	if app.Version {
-		fmt.Printf(SIGNATURE)
-		fmt.Println("Version:", VERSION)
-		os.Exit(0)
+func isCommand(commands []string, arg string) bool {
+	for _, command := range commands {
+		if arg == command {
+			return true
+		}
 	}
+	return false
+}
 
-	if app.TimeRemaining || app.PrintWeek || app.History || app.PrintMonth {
-		return
+func (app *App) validateLog() error {
+	if len(app.Args) > 0 {
+		return usageErrorf("log doesn't take any arguments. give the ticket with -r")
+	}
+	if app.Ticket == "" {
+		return usageErrorf("please provide a ticket reference. -r")
 	}
+	if app.TimeSpent == "" {
+		return usageErrorf("no time given. -t")
+	}
+	return app.parseSpent()
+}
 
-	if app.Encode != "" {
-		app.CredentialEncode()
-		os.Exit(0)
+// parseSpent reads the time spent given by -t, with the working day and week of the profile.
+func (app *App) parseSpent() error {
+	spent, err := ParseDuration(app.TimeSpent, app.schedule())
+	if err != nil {
+		return usageErrorf("-t: %v", err)
 	}
+	app.Spent = spent
+	return nil
+}
 
+func (app *App) validateWorklogChange() error {
+	if len(app.Args) != 1 {
+		return usageErrorf("please provide the ID of the worklog. e.g. timesheet %s 10042 -r DDSP-4", app.Command)
+	}
+	if app.Command == "edit" {
+		app.EditId = app.Args[0]
+	} else {
+		app.DeleteId = app.Args[0]
+	}
 	if app.Ticket == "" {
-		panic(errors.New("please provide a ticket reference. -r"))
+		return usageErrorf("please provide the ticket reference of the worklog. -r")
 	}
 
-	if app.TimeSpent == "" {
-		panic(errors.New("no time given. -t"))
+	var validMode bool
+	for _, mode := range jira.EstimateModes {
+		validMode = validMode || app.Estimate == mode
+	}
+	if !validMode {
+		return usageErrorf("unknown estimate adjustment %q. use one of: %s", app.Estimate, strings.Join(jira.EstimateModes, ", "))
+	}
+	if app.Estimate == "new" && app.NewEstimate == "" {
+		return usageErrorf("-estimate new requires -new-estimate")
+	}
+	if app.Estimate == "manual" && app.EditId != "" {
+		return usageErrorf("-estimate manual is only available with delete")
 	}
+	if app.Estimate == "manual" && app.IncreaseBy == "" {
+		return usageErrorf("-estimate manual requires -increase-by")
+	}
+
+	if app.EditId != "" && app.TimeSpent == "" && !app.isStartSet() && !app.isFlagSet("m") {
+		return usageErrorf("nothing to update. give at least one of -t, -d, -at or -m")
+	}
+	if app.TimeSpent != "" {
+		return app.parseSpent()
+	}
+	return nil
+}
+
+func (app *App) validateTimer() error {
+	if app.Command == "start" || app.Command == "switch" {
+		if len(app.Args) != 1 {
+			return usageErrorf("please provide a ticket reference. e.g. timesheet %s DDSP-4", app.Command)
+		}
+		app.Ticket = app.Args[0]
+	}
+	if (app.Command == "stop" || app.Command == "status") && len(app.Args) > 0 {
+		return usageErrorf("%s doesn't take a ticket reference", app.Command)
+	}
+	if _, err := parseRoundingRule(app.Round); err != nil {
+		return &UsageError{Msg: err.Error()}
+	}
+	return nil
 }
 
-func (app *App) usage() {
-	fmt.Printf("timesheet (-r -t [-d] [-m]] [[-h] [-e] [-d]) ([-remaining] [-history])\n")
-	flag.PrintDefaults()
-	fmt.Printf("Example:\n" +
-		"\ttimesheet -r DDSP-XXXX -t 8h -m \"Jenkins pipeline completed\"\n" +
-		"\ttimesheet -r DDSP-XXXX -t 1h -m \"Investigated possible solutions\" -d 2020-03-05\n" +
-		"\ttimesheet -remaining\n" +
-		"\ttimesheet -remaining -d 2020-03-05\n" +
-		"\ttimesheet -history\n" +
-		"\ttimesheet -history -d -1\n")
+func (app *App) validateQueue() error {
+	if app.Command == "sync" {
+		if len(app.Args) > 0 {
+			return usageErrorf("sync doesn't take any arguments")
+		}
+		return nil
+	}
+
+	if len(app.Args) == 0 || app.Args[0] == "list" {
+		if len(app.Args) > 1 {
+			return usageErrorf("queue list doesn't take any arguments")
+		}
+		return nil
+	}
+	if !isCommand(queueActions, app.Args[0]) {
+		return usageErrorf("unknown queue action %q. use one of: %s", app.Args[0], strings.Join(queueActions, ", "))
+	}
+	if len(app.Args) != 2 {
+		return usageErrorf("please provide the number of the queued worklog. e.g. timesheet queue %s 2", app.Args[0])
+	}
+	id, err := strconv.Atoi(app.Args[1])
+	if err != nil {
+		return usageErrorf("queued worklog number %q isn't a number", app.Args[1])
+	}
+	app.QueueId = id
+
+	if app.Args[0] == "edit" && app.TimeSpent == "" && !app.isFlagSet("r") && !app.isStartSet() && !app.isFlagSet("m") {
+		return usageErrorf("nothing to update. give at least one of -r, -t, -d, -at or -m")
+	}
+	if app.TimeSpent != "" {
+		return app.parseSpent()
+	}
+	return nil
+}
+
+func (app *App) validateReport() error {
+	if len(app.Args) > 0 {
+		return usageErrorf("%s doesn't take any arguments", app.Command)
+	}
+	if _, err := newFormatter(app.Format); err != nil {
+		return err
+	}
+	return app.validateGroupBy()
+}
+
+func (app *App) validateRange() error {
+	if len(app.Args) > 1 {
+		return usageErrorf("range takes a single period. e.g. timesheet range last-quarter")
+	}
+	if len(app.Args) == 1 && (app.From != "" || app.To != "") {
+		return usageErrorf("give either a period or -from and -to, not both")
+	}
+	if len(app.Args) == 0 && app.From == "" {
+		return usageErrorf("please provide a period or -from. e.g. timesheet range this-month")
+	}
+	if !isCommand(rangeIntervals, app.Interval) {
+		return usageErrorf("unknown interval %q. use one of: %s", app.Interval, strings.Join(rangeIntervals, ", "))
+	}
+	if _, err := newFormatter(app.Format); err != nil {
+		return err
+	}
+	return app.validateGroupBy()
+}
+
+func (app *App) validateGroupBy() error {
+	if app.GroupBy != "" && !isCommand(reportGroups, app.GroupBy) {
+		return usageErrorf("unknown grouping %q. use one of: %s", app.GroupBy, strings.Join(reportGroups, ", "))
+	}
+	return nil
+}
+
+func (app *App) validateConfig() error {
+	if len(app.Args) == 0 {
+		return usageErrorf("please provide a config action. use one of: login, encode")
+	}
+	switch app.Args[0] {
+	case "login":
+		if len(app.Args) > 1 {
+			return usageErrorf("config login doesn't take any arguments")
+		}
+	case "encode":
+		if len(app.Args) != 2 {
+			return usageErrorf("please provide the credentials to encode. e.g. timesheet config encode 'email:token;domain'")
+		}
+		app.Encode = app.Args[1]
+	default:
+		return usageErrorf("unknown config action %q. use one of: login, encode", app.Args[0])
+	}
+	return nil
+}
+
+func (app *App) validateCompletion() error {
+	if len(app.Args) != 1 || !isCommand(completionShells, app.Args[0]) {
+		return usageErrorf("please provide the shell. use one of: %s", strings.Join(completionShells, ", "))
+	}
+	return nil
+}
+
+func (app *App) validateHelp() error {
+	if len(app.Args) > 1 || (len(app.Args) == 1 && findCommand(app.Args[0]) == nil) {
+		return usageErrorf("help takes the name of a command. see timesheet help")
+	}
+	return nil
+}
+
+// isFlagSet tells whether the flag was given on the command line, as opposed to holding its default.
+func (app *App) isFlagSet(name string) bool {
+	var found bool
+	if app.flags == nil {
+		return false
+	}
+	app.flags.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			found = true
+		}
+	})
+	return found
+}
+
+// printUsage lists the commands.
+func printUsage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: timesheet COMMAND [ARGUMENTS] [FLAGS]\n\nCommands:\n")
+	for _, command := range commands {
+		if !command.Hidden {
+			fmt.Fprintf(w, "  %-10s %s\n", command.Name, command.Summary)
+		}
+	}
+	fmt.Fprintf(w, "\nRun timesheet help COMMAND for the flags and examples of a command.\n")
+}
+
+// help writes the usage of the command with its flags and examples.
+func (c *Command) help(w io.Writer, flags *flag.FlagSet) {
+	fmt.Fprintf(w, "Usage: timesheet %s", c.Name)
+	if c.Args != "" {
+		fmt.Fprintf(w, " %s", c.Args)
+	}
+	fmt.Fprintf(w, " [FLAGS]\n\n%s\n", c.Summary)
+	var hasFlags bool
+	flags.VisitAll(func(*flag.Flag) { hasFlags = true })
+	if hasFlags {
+		fmt.Fprintf(w, "\nFlags:\n")
+		flags.SetOutput(w)
+		flags.PrintDefaults()
+	}
+	if len(c.Examples) > 0 {
+		fmt.Fprintf(w, "\nExamples:\n")
+		for _, example := range c.Examples {
+			fmt.Fprintf(w, "  timesheet %s\n", example)
+		}
+	}
 }
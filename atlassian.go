@@ -1,14 +1,17 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"io"
-	"net/http"
 	"sort"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
+
+	"github.com/praveenprem/timesheet/jira"
 )
 
 /**
@@ -18,69 +21,17 @@ import (
  * Created on: 01/03/2020 18:34
  */
 
-var secondsInDay = 28800
-
 type (
-	TimeLog struct {
-		Started   string   `json:"started"`
-		TimeSpent string   `json:"timeSpent"`
-		Comment   *Comment `json:"comment"`
-	}
-
-	Comment struct {
-		Version     int    `json:"version"`
-		CommentType string `json:"type"`
-		Content     []*Doc `json:"content"`
-	}
-
-	Doc struct {
-		ContentType string       `json:"type"`
-		Content     []*Paragraph `json:"content"`
-	}
-
-	Paragraph struct {
-		Text     string `json:"text"`
-		TextType string `json:"type"`
-	}
-
-	Response struct {
-		ErrorMessages []string `json:"errorMessages"`
-	}
-
-	JiraSearchResult struct {
-		StartAt    int `json:"startAt"`
-		MaxResults int `json:"maxResults"`
-		Total      int `json:"total"`
-		Issues     []struct {
-			Id     string `json:"id"`
-			Key    string `json:"key"`
-			Fields struct {
-				Summary string `json:"summary"`
-			} `json:"fields"`
-		} `json:"issues"`
-	}
-
-	WorkLogs struct {
-		Key      string
-		Summary  string
-		Total    int       `json:"total"`
-		Worklogs []Worklog `json:"worklogs"`
-	}
-
-	Worklog struct {
-		TimeSpentSeconds int    `json:"timeSpentSeconds"`
-		IssueId          string `json:"issueId"`
-		Started          string `json:"started"`
-		Author           struct {
-			EmailAddress string `json:"emailAddress"`
-			DisplayName  string `json:"displayName"`
-		} `json:"author"`
-		Comment *Comment `json:"comment"`
-	}
-
+	// WeekLog is the week table. Issues are the rows, named Label ("Issue" by default), with a total
+	// column when Subtotals is set. Durations is the duration_format the times are written in.
 	WeekLog struct {
-		Total  int
-		Issues []Issue
+		Total     int
+		Columns   []string
+		Balance   map[string]DayBalance
+		Issues    []Issue
+		Label     string
+		Subtotals bool
+		Durations string
 	}
 
 	Issue struct {
@@ -95,357 +46,240 @@ type (
 	}
 
 	Week struct {
-		Total int
-		Days  map[string]map[string][]int
+		Total   int
+		Columns []string
+		Days    map[string]map[string][]int
 	}
 
+	// NumberWeek is a week of the month table. Groups holds the time of each group of -group-by by day.
 	NumberWeek struct {
 		Week
-		Number int
+		Number  int
+		Balance map[string]DayBalance
+		Groups  map[string]map[string]int
 	}
 
 	Month struct {
-		Total int
-		Weeks []NumberWeek
+		Total        int
+		Expected     int
+		SecondsInDay int
+		Columns      []string
+		Weeks        []NumberWeek
+		Durations    string
 	}
 )
 
-var daysOfWeek = []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday"}
-
-func LogTime(reference string, time string, started string, comment string, domain string, auth string) {
-	var slot = TimeLog{}
-	slot.TimeSpent = time
+// LogTime books the time on the issue and confirms it on out with the summary of the issue, when known.
+func LogTime(ctx context.Context, client jira.API, out io.Writer, reference string, summary string, spent Duration, started string, comment string) (*jira.Worklog, error) {
+	var slot = jira.TimeLog{}
+	slot.TimeSpentSeconds = spent.Seconds()
 	slot.Started = started
 
 	if comment != "" {
-		var slotComment = Comment{}
-		var doc = Doc{}
-		var paragraph = Paragraph{}
-		paragraph.Text = comment
-		paragraph.TextType = "text"
-		doc.ContentType = "paragraph"
-		doc.Content = append(doc.Content, &paragraph)
-		slotComment.Version = 1
-		slotComment.CommentType = "doc"
-		slotComment.Content = append(slotComment.Content, &doc)
-		slot.Comment = &slotComment
-	}
-	resp, err := slot.post(reference, domain, auth)
+		slot.Comment = jira.NewComment(comment)
+	}
+	worklog, err := client.AddWorklog(ctx, reference, &slot)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("booking %s to %s: %w", spent, reference, err)
 	}
 
-	if len(resp.ErrorMessages) > 0 {
-		panic(resp.ErrorMessages)
+	if summary != "" {
+		fmt.Fprintf(out, "%s booked to issue %s: %s\n", spent, reference, summary)
+	} else {
+		fmt.Fprintf(out, "%s booked to issue %s\n", spent, reference)
 	}
-
-	fmt.Printf("%s booked to issue %s\n", slot.TimeSpent, reference)
+	return worklog, nil
 }
 
-func (app *App) GetTimeRemaining(domain string, auth string) {
-	var totalTimeSpent int
-	var timeRemaining float64
-	userEmail, _ := basicAuth(auth)
-	issuesOfTheDay, iErr := getIssuesUpdatedToday(domain, auth, app.getDate())
-	if iErr != nil {
-		panic(iErr)
-	}
-
-	workLogs, wErr := issuesOfTheDay.getWorklogs(domain, auth)
-	if wErr != nil {
-		panic(wErr)
-	}
-
-	for _, wLog := range workLogs {
-		if wLog.Total > 0 {
-			for _, log := range wLog.Worklogs {
-				if app.isDateMatch(log.Started) {
-					if log.Author.EmailAddress == userEmail {
-						totalTimeSpent += log.TimeSpentSeconds
-					}
-				}
-			}
-		}
+func (app *App) GetTimeRemaining(ctx context.Context) error {
+	day, dErr := time.Parse(YmdFormat, app.getDate())
+	if dErr != nil {
+		return dErr
 	}
-
-	timeRemaining = getInHours(secondsInDay - totalTimeSpent)
-	if timeRemaining < 0 {
-		fmt.Printf("oops... Looks like you've booked %.2f hours more that what you supposed to!", timeRemaining)
-	} else {
-		fmt.Printf("You've %.2f hours ramaining!\n", timeRemaining)
+	entries, err := app.collectEntries(ctx, day, day, app.isDateMatch)
+	if err != nil {
+		return err
 	}
 
+	var report = newReport(ReportRemaining, day, day, entries)
+	report.DaySeconds = app.schedule().secondsOn(day)
+	app.addBalance(report)
+	report.Remaining = report.Expected - report.Total
+	return app.render(report)
 }
 
-func (app *App) GetHistory() {
-	var totalTimeSpent int
-	userEmail, _ := basicAuth(app.Configuration.Auth)
-	issuesOfTheDay, iErr := getIssuesUpdatedToday(app.Configuration.Domain, app.Configuration.Auth, app.getDate())
-	if iErr != nil {
-		panic(iErr)
-	}
-
-	workLogs, wErr := issuesOfTheDay.getWorklogs(app.Configuration.Domain, app.Configuration.Auth)
-	if wErr != nil {
-		panic(wErr)
-	}
-
-	fmt.Printf("Timesheet history: (%s):\n", app.getDate())
-
-	for _, wLog := range workLogs {
-		if wLog.Total > 0 {
-			for _, log := range wLog.Worklogs {
-				if app.isDateMatch(log.Started) {
-					if log.Author.EmailAddress == userEmail {
-						fmt.Printf("\t%s:\n\t\t%s: %s\n\t\t%s: %s\n\t\t%s: %s\n\t\t%s: %.2fh\n\n",
-							wLog.Key,
-							"Summary", wLog.Summary,
-							"Author", log.Author.DisplayName,
-							"Comment", log.Comment.Content[0].Content[0].Text,
-							"Time spent", getInHours(log.TimeSpentSeconds),
-						)
-						totalTimeSpent += log.TimeSpentSeconds
-					}
-				}
-			}
-		}
+func (app *App) GetHistory(ctx context.Context) error {
+	day, dErr := time.Parse(YmdFormat, app.getDate())
+	if dErr != nil {
+		return dErr
+	}
+	entries, err := app.collectEntries(ctx, day, day, app.isDateMatch)
+	if err != nil {
+		return err
 	}
 
-	fmt.Println(fmt.Sprintf("Total %.1fh", getInHours(totalTimeSpent)))
-
+	var report = newReport(ReportDay, day, day, entries)
+	report.DaySeconds = app.schedule().secondsOn(day)
+	app.addBalance(report)
+	return app.render(report)
 }
 
-func (app *App) GetWeekTimesheet(domain string, auth string) {
-	var weekLog WeekLog
-	start, end := app.getWeek()
-	userEmail, _ := basicAuth(auth)
-	issuesOfTheWeek, iErr := getIssuesUpdatedBetweenDays(domain, auth,
-		start.Format("2006-01-02"), end.Format("2006-01-02"))
-	if iErr != nil {
-		panic(iErr)
-	}
-
-	worklogs, wErr := issuesOfTheWeek.getWorklogs(domain, auth)
-	if wErr != nil {
-		panic(wErr)
+func (app *App) GetWeekTimesheet(ctx context.Context) error {
+	start, end, err := app.getWeek()
+	if err != nil {
+		return err
 	}
-
-	for _, wLog := range worklogs {
-		var issue Issue
-		issue.Key = wLog.Key
-		if wLog.Total > 0 {
-			for _, log := range wLog.Worklogs {
-				if app.isDateBetween(log.Started, start, end) {
-					if log.Author.EmailAddress == userEmail {
-						var dayLog DayLog
-						dayLog.WeekDay = getDateOfWeek(log.Started)
-						dayLog.TimeSpent = log.TimeSpentSeconds
-						weekLog.Total += log.TimeSpentSeconds
-						issue.Logs = append(issue.Logs, dayLog)
-					}
-				}
-			}
-		}
-		if len(issue.Logs) > 0 {
-			weekLog.Issues = append(weekLog.Issues, issue)
-		}
+	entries, err := app.collectEntries(ctx, start, end, func(started string) bool {
+		return app.isDateBetween(started, start, end)
+	})
+	if err != nil {
+		return err
 	}
 
-	weekLog.print()
+	var report = newReport(ReportWeek, start, end, entries)
+	report.DaySeconds = app.schedule().averageDay()
+	app.groupRows(report, start, end)
+	app.addBalance(report)
+	return app.render(report)
 }
 
-func (app *App) GetMonthTimesheet(domain string, auth string) {
-	var month Month
-
-	userEmail, _ := basicAuth(auth)
-	start, end, weekNumbers := app.getMonth()
-
-	issuesOfTheMonth, iErr := getIssuesUpdatedBetweenDays(domain, auth,
-		start.Format("2006-01-02"), end.Format("2006-01-02"))
-	if iErr != nil {
-		panic(iErr)
-	}
-
-	worklogs, wErr := issuesOfTheMonth.getWorklogs(domain, auth)
-	if wErr != nil {
-		panic(wErr)
+func (app *App) GetMonthTimesheet(ctx context.Context) error {
+	start, end, _, err := app.getMonth()
+	if err != nil {
+		return err
 	}
-
-	for wNum, dates := range weekNumbers {
-		weekLog := app.filterByDates(filterByUser(userEmail, worklogs), dates[0], dates[len(dates)-1])
-		sortedWeek := weekLog.sort()
-		month.Total += weekLog.Total
-		var numWeek NumberWeek
-		numWeek.Week = sortedWeek
-		numWeek.Number = wNum
-		month.Weeks = append(month.Weeks, numWeek)
+	entries, err := app.collectEntries(ctx, start, end, func(started string) bool {
+		return app.isDateBetween(started, start, end)
+	})
+	if err != nil {
+		return err
 	}
 
-	month.print()
+	var report = newReport(ReportMonth, start, end, entries)
+	report.DaySeconds = app.schedule().averageDay()
+	app.groupRows(report, start, end)
+	app.addBalance(report)
+	return app.render(report)
 }
 
-func getIssuesUpdatedToday(domain string, auth string, date string) (*JiraSearchResult, error) {
-	var client = &http.Client{}
-	var query = fmt.Sprintf("jql=worklogDate%%20>%%3D%%20\"%s\"%%20AND%%20worklogDate%%20<%%3D%%20\"%s\"", date, date)
-	var url = fmt.Sprintf("https://%s/rest/api/3/search?%s", strings.TrimSuffix(domain, "\n"), query)
-	req, reqErr := http.NewRequest("GET", url, nil)
-	if reqErr != nil {
-		panic(reqErr)
+// groupRows sums the entries of the week and month reports by week and by the group of -group-by, the rows
+// their csv, tsv and json output list instead of the worklogs.
+func (app *App) groupRows(report *Report, start time.Time, end time.Time) {
+	if app.GroupBy == "" {
+		return
 	}
+	report.Interval, report.GroupBy = "week", app.GroupBy
+	report.Rows = rangeRows(report.Entries, start, end, report.Interval, report.GroupBy, app.schedule())
+}
 
-	req.Header.Add("Content-Type", "application/json")
-	req.SetBasicAuth(basicAuth(auth))
+// currentUser resolves the user the reports are for, once per run. Sites without /myself fall back to
+// the email address of the credentials.
+func (app *App) currentUser(ctx context.Context) (*jira.User, error) {
+	if app.User != nil {
+		return app.User, nil
+	}
 
-	resp, err := client.Do(req)
+	user, err := app.Client.Myself(ctx)
+	var notFoundErr *jira.NotFoundError
+	if errors.As(err, &notFoundErr) {
+		user, err = &jira.User{}, nil
+	}
 	if err != nil {
 		return nil, err
 	}
-
-	defer resp.Body.Close()
-
-	var response = new(JiraSearchResult)
-	decodeErr := json.NewDecoder(resp.Body).Decode(&response)
-	if decodeErr != nil {
-		panic(decodeErr)
+	if user.EmailAddress == "" {
+		user.EmailAddress, _ = basicAuth(app.Configuration.Auth)
 	}
-	return response, nil
+	app.User = user
+	return user, nil
 }
 
-func getIssuesUpdatedBetweenDays(domain string, auth string, start string, end string) (*JiraSearchResult, error) {
-	var result JiraSearchResult
-
-	for true {
-		var query = fmt.Sprintf("startAt=%d&maxResults=50&jql=worklogDate%%20>%%3D%%20\"%s\"%%20AND%%20worklogDate%%20<%%3D%%20\"%s\"", result.StartAt, start, end)
-		var url = fmt.Sprintf("https://%s/rest/api/3/search?%s", strings.TrimSuffix(domain, "\n"), query)
-
-		var headers []struct {
-			key   string
-			value string
-		}
-
-		headers = append(headers, struct {
-			key   string
-			value string
-		}{key: "Content-Type", value: "application/json"})
-		var response = new(JiraSearchResult)
-		decodeErr := json.NewDecoder(httpReq("GET", url, auth, nil, headers).Body).Decode(&response)
-		if decodeErr != nil {
-			panic(decodeErr)
-		}
-		result.StartAt += response.MaxResults
-		result.MaxResults = response.MaxResults
-		result.Total = response.Total
-		for _, issue := range response.Issues {
-			result.Issues = append(result.Issues, issue)
-		}
-		if result.StartAt >= result.Total {
-			break
-		}
+// isAuthor tells whether the worklog was booked by the user, comparing account IDs (Cloud) or user
+// names (Server) when both are known and email addresses otherwise.
+func isAuthor(user *jira.User, log jira.Worklog) bool {
+	if user.AccountId != "" && log.Author.AccountId != "" {
+		return user.AccountId == log.Author.AccountId
+	}
+	if user.Name != "" && log.Author.Name != "" {
+		return user.Name == log.Author.Name
 	}
-	return &result, nil
+	return user.EmailAddress != "" && strings.EqualFold(user.EmailAddress, log.Author.EmailAddress)
 }
 
-func (issues *JiraSearchResult) getWorklogs(domain string, auth string) ([]WorkLogs, error) {
-	var worklogs []WorkLogs
-	for _, issue := range issues.Issues {
-		var client = &http.Client{}
-		var url = fmt.Sprintf("https://%s/rest/api/3/issue/%s/worklog", strings.TrimSuffix(domain, "\n"), issue.Key)
-
-		req, reqErr := http.NewRequest("GET", url, nil)
-		if reqErr != nil {
-			panic(reqErr)
-		}
-
-		req.Header.Add("Content-Type", "application/json")
-		req.SetBasicAuth(basicAuth(auth))
-
-		resp, err := client.Do(req)
-		if err != nil {
-			panic(err)
-		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode != http.StatusOK {
-			panic(resp.Status)
-		}
+// getIssuesUpdatedBetweenDays searches the issues with worklogs between the start and end dates. Jira compares
+// worklog dates in the timezone of the user's Jira profile, so the search takes in a day on each side.
+func getIssuesUpdatedBetweenDays(ctx context.Context, client jira.API, start time.Time, end time.Time) (*jira.SearchResult, error) {
+	return client.Search(ctx, fmt.Sprintf("worklogDate >= \"%s\" AND worklogDate <= \"%s\"",
+		start.AddDate(0, 0, -1).Format(YmdFormat), end.AddDate(0, 0, 1).Format(YmdFormat)))
+}
 
-		var response = WorkLogs{}
-		response.Key = issue.Key
-		response.Summary = issue.Fields.Summary
-		decodeErr := json.NewDecoder(resp.Body).Decode(&response)
-		if decodeErr != nil {
-			panic(decodeErr)
+// getWorklogs fetches the worklogs of each issue started between the start and end dates, running up to
+// app.Concurrency requests at a time. Results keep the order of the search result and the first failure
+// cancels the requests still in flight. The window is widened by a day on each side so entries booked in a
+// timezone away from UTC are not cut off; callers still filter by the exact dates.
+func (app *App) getWorklogs(ctx context.Context, issues *jira.SearchResult, start time.Time, end time.Time) ([]jira.WorkLogs, error) {
+	var worklogs = make([]jira.WorkLogs, len(issues.Issues))
+	var startedAfter, _ = fullDay(start.AddDate(0, 0, -1))
+	var _, startedBefore = fullDay(end.AddDate(0, 0, 1))
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+		limit    = app.Concurrency
+	)
+	if limit < 1 {
+		limit = 1
+	}
+	var slots = make(chan struct{}, limit)
+
+	for i, issue := range issues.Issues {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
 		}
 
-		worklogs = append(worklogs, response)
+		wg.Add(1)
+		go func(i int, issue jira.SearchIssue) {
+			defer wg.Done()
+			defer func() { <-slots }()
 
+			response, err := app.Client.Worklogs(ctx, issue.Key, startedAfter, startedBefore)
+			if err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+			response.Key = issue.Key
+			response.Summary = issue.Fields.Summary
+			worklogs[i] = *response
+		}(i, issue)
 	}
-	return worklogs, nil
-}
+	wg.Wait()
 
-func (slot *TimeLog) post(issueId string, domain string, auth string) (*Response, error) {
-	client := &http.Client{}
-	var url = fmt.Sprintf("https://%s/rest/api/3/issue/%s/worklog", strings.TrimSuffix(domain, "\n"), issueId)
-
-	req, reqErr := http.NewRequest("POST", url, bytes.NewBuffer(slot.json()))
-	if reqErr != nil {
-		panic(reqErr)
+	if firstErr != nil {
+		return nil, firstErr
 	}
-
-	loginDetails := strings.Split(auth, ":")
-	req.Header.Add("Content-Type", "application/json")
-	req.SetBasicAuth(loginDetails[0], loginDetails[1])
-
-	resp, err := client.Do(req)
-
-	if err != nil {
+	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
-
-	defer resp.Body.Close()
-	var response = new(Response)
-	decodeErr := json.NewDecoder(resp.Body).Decode(&response)
-	if decodeErr != nil {
-		panic(decodeErr)
-	}
-	return response, nil
-}
-
-func (slot *TimeLog) json() []byte {
-	body, err := json.Marshal(slot)
-	if err != nil {
-		panic(err)
-	}
-	return body
+	return worklogs, nil
 }
 
 func basicAuth(token string) (string, string) {
-	var loginDetails = strings.Split(token, ":")
-	return loginDetails[0], loginDetails[1]
-}
-
-func httpReq(method string, url string, auth string, body io.Reader, headers []struct {
-	key   string
-	value string
-}) *http.Response {
-	var client = &http.Client{}
-	req, reqErr := http.NewRequest(method, url, body)
-	if reqErr != nil {
-		panic(reqErr)
-	}
-
-	for _, header := range headers {
-		req.Header.Add(header.key, header.value)
-	}
-	req.SetBasicAuth(basicAuth(auth))
-	resp, err := client.Do(req)
-	if err != nil {
-		panic(err)
+	var loginDetails = strings.SplitN(token, ":", 2)
+	if len(loginDetails) < 2 {
+		return loginDetails[0], ""
 	}
-
-	//defer resp.Body.Close()
-	return resp
-
+	return loginDetails[0], loginDetails[1]
 }
 
 func (w *WeekLog) sort() Week {
@@ -454,6 +288,7 @@ func (w *WeekLog) sort() Week {
 	sortedWeek.Days = make(map[string]map[string][]int)
 
 	sortedWeek.Total = w.Total
+	sortedWeek.Columns = w.Columns
 	for _, issue := range w.Issues {
 		sortedWeek.Days[issue.Key] = make(map[string][]int)
 		for _, day := range issue.Logs {
@@ -466,7 +301,7 @@ func (w *WeekLog) sort() Week {
 
 func (w *Week) fillGaps() Week {
 	for _, days := range w.Days {
-		for _, d := range daysOfWeek {
+		for _, d := range w.Columns {
 			if _, found := days[d]; !found {
 				days[d] = []int{0}
 			}
@@ -478,7 +313,7 @@ func (w *Week) fillGaps() Week {
 
 func (w *Week) sum() Week {
 	for _, days := range w.Days {
-		for _, d := range daysOfWeek {
+		for _, d := range w.Columns {
 			if times, found := days[d]; !found {
 				sum := 0
 				for _, t := range times {
@@ -492,92 +327,143 @@ func (w *Week) sum() Week {
 	return *w
 }
 
-func (w *WeekLog) print() {
-	for i := 0; i <= 83; i++ {
-		if i == 0 {
-			fmt.Printf(" ")
-		} else if i == 83 {
-			fmt.Printf("\n")
-		} else {
-			fmt.Printf("_")
-		}
+func (w *WeekLog) print(out io.Writer) {
+	// The first column fits the longest issue key or group name.
+	var keyWidth = 15
+	for _, issue := range w.Issues {
+		keyWidth = max(keyWidth, utf8.RuneCountInString(issue.Key))
+	}
+	var widths = []int{keyWidth + 2}
+	for range w.Columns {
+		widths = append(widths, 12)
+	}
+	var label, totalWidth = w.Label, -1
+	if label == "" {
+		label = "Issue"
+	}
+	if w.Subtotals {
+		widths = append(widths, 12)
+		totalWidth = 10
 	}
 
-	fmt.Printf("| %-15s ", "Issue")
-	for _, title := range daysOfWeek {
-		fmt.Printf("| %-10s ", title)
-	}
-	fmt.Printf("|\n")
-	for i := 0; i <= 82; i++ {
-		if i == 0 {
-			fmt.Printf("|")
-		} else if i == 18 || i == 31 || i == 44 || i == 57 || i == 70 {
-			fmt.Printf("|")
-		} else if i == 82 {
-			fmt.Printf("_|\n")
-		} else {
-			fmt.Printf("_")
-		}
+	printTableTop(out, widths)
+	fmt.Fprintf(out, "| %-*s ", keyWidth, label)
+	for _, title := range w.Columns {
+		fmt.Fprintf(out, "| %-10s ", title)
+	}
+	if w.Subtotals {
+		fmt.Fprintf(out, "| %-10s ", "Total")
 	}
+	fmt.Fprintf(out, "|\n")
+	printTableRule(out, widths, "_")
 
 	weekSorted := w.sort()
 	var processedIssues int
+	var days = weekSorted.sum().Days
+	var keys []string
+	for key := range days {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
 
-	for issue, day := range weekSorted.sum().Days {
+	for _, issue := range keys {
+		var day = days[issue]
 		if processedIssues > 0 {
-			for i := 0; i <= 83; i++ {
-				if i == 0 {
-					fmt.Printf("|")
-				} else if i == 83 {
-					fmt.Printf("|\n")
-				} else if i == 18 || i == 31 || i == 44 || i == 57 || i == 70 {
-					fmt.Printf("|")
-				} else {
-					fmt.Printf("-")
-				}
-			}
+			printTableRule(out, widths, "-")
 		}
-		fmt.Printf("| %-15s ", issue)
-		for _, dDay := range daysOfWeek {
+		fmt.Fprintf(out, "| %-*s ", keyWidth, issue)
+		var issueTotal int
+		for _, dDay := range w.Columns {
 			var dDayTotal int
 			for _, dDayTime := range day[dDay] {
 				dDayTotal += dDayTime
 			}
+			issueTotal += dDayTotal
 			if dDayTotal == 0 {
-				fmt.Printf("| %-10s ", "")
+				fmt.Fprintf(out, "| %-10s ", "")
 			} else {
-				fmt.Printf("| %-10.1f ", getInHours(dDayTotal))
+				fmt.Fprintf(out, "| %-10s ", Duration(dDayTotal).Text(w.Durations, "%.1f"))
 			}
 		}
-		fmt.Println("|")
+		if w.Subtotals {
+			fmt.Fprintf(out, "| %-10s ", Duration(issueTotal).Text(w.Durations, "%.1f"))
+		}
+		fmt.Fprintln(out, "|")
 		processedIssues += 1
 	}
 
-	for i := 0; i <= 83; i++ {
-		if i == 0 {
-			fmt.Printf("|")
-		} else if i == 83 {
-			fmt.Printf("|\n")
-		} else if i == 18 || i == 31 || i == 44 || i == 57 || i == 70 {
-			fmt.Printf("|")
-		} else {
-			fmt.Printf("_")
+	if len(w.Balance) > 0 {
+		printTableRule(out, widths, "_")
+		printBalance(out, fmt.Sprintf("%%-%ds", keyWidth), w.Columns, w.Balance, totalWidth, true, w.Durations)
+	}
+
+	printTableRule(out, widths, "_")
+
+	fmt.Fprintln(out, fmt.Sprintf("Total %s", Duration(weekSorted.Total).Text(w.Durations, "%.1fh")))
+}
+
+// printBalance writes the expected and difference rows of the days in columns, after the booked row when
+// withBooked is set, with the label in the given format. A total column of totalWidth is added unless
+// totalWidth is negative.
+func printBalance(out io.Writer, label string, columns []string, balance map[string]DayBalance, totalWidth int, withBooked bool, durations string) {
+	var rows = []struct {
+		name   string
+		format string
+		value  func(day DayBalance) int
+	}{
+		{"Booked", "%.1f", func(day DayBalance) int { return day.Booked }},
+		{"Expected", "%.1f", func(day DayBalance) int { return day.Expected }},
+		{"Difference", "%+.1f", func(day DayBalance) int { return day.Difference }},
+	}
+	if !withBooked {
+		rows = rows[1:]
+	}
+	for _, row := range rows {
+		fmt.Fprintf(out, "| "+label+" ", row.name)
+		var total int
+		for _, column := range columns {
+			day, found := balance[column]
+			if !found {
+				fmt.Fprintf(out, "| %-10s ", "")
+				continue
+			}
+			total += row.value(day)
+			fmt.Fprintf(out, "| %-10s ", Duration(row.value(day)).Text(durations, row.format))
+		}
+		if totalWidth >= 0 {
+			fmt.Fprintf(out, "| %-*s ", totalWidth, Duration(total).Text(durations, row.format))
 		}
+		fmt.Fprintln(out, "|")
 	}
+}
 
-	fmt.Println(fmt.Sprintf("Total %.1fh", getInHours(weekSorted.Total)))
+// printTableTop draws the line above a table with columns of the given widths.
+func printTableTop(out io.Writer, widths []int) {
+	var width = len(widths) - 1
+	for _, w := range widths {
+		width += w
+	}
+	fmt.Fprintf(out, " %s\n", strings.Repeat("_", width))
 }
 
-func filterByUser(userEmail string, worklogs []WorkLogs) []WorkLogs {
-	var userLogs []WorkLogs
+// printTableRule draws a line between the rows of a table with columns of the given widths.
+func printTableRule(out io.Writer, widths []int, fill string) {
+	for _, w := range widths {
+		fmt.Fprintf(out, "|%s", strings.Repeat(fill, w))
+	}
+	fmt.Fprintf(out, "|\n")
+}
+
+func filterByUser(user *jira.User, worklogs []jira.WorkLogs) []jira.WorkLogs {
+	var userLogs []jira.WorkLogs
 	for _, wLog := range worklogs {
-		i := WorkLogs{
+		i := jira.WorkLogs{
 			Key:     wLog.Key,
 			Summary: wLog.Summary,
 			Total:   wLog.Total,
 		}
 		for _, log := range wLog.Worklogs {
-			if log.Author.EmailAddress == userEmail {
+			if isAuthor(user, log) {
 				i.Worklogs = append(i.Worklogs, log)
 			}
 		}
@@ -588,37 +474,20 @@ func filterByUser(userEmail string, worklogs []WorkLogs) []WorkLogs {
 	return userLogs
 }
 
-func (app *App) filterByDates(worklogs []WorkLogs, start time.Time, end time.Time) WeekLog {
-	var weekLog WeekLog
-	for _, wLog := range worklogs {
-		var issue = Issue{
-			Key: wLog.Key,
-		}
-		if wLog.Total > 0 {
-			for _, log := range wLog.Worklogs {
-				if app.isDateBetween(log.Started, start, end) {
-					var dayLog DayLog
-					dayLog.WeekDay = getDateOfWeek(log.Started)
-					dayLog.TimeSpent = log.TimeSpentSeconds
-					weekLog.Total += log.TimeSpentSeconds
-					issue.Logs = append(issue.Logs, dayLog)
-				}
-			}
-		}
-
-		if len(issue.Logs) > 0 {
-			weekLog.Issues = append(weekLog.Issues, issue)
-		}
-	}
-	return weekLog
-}
-
-func (m *Month) print() {
+func (m *Month) print(out io.Writer) {
 	var month = make(map[int]map[string]int)
+	var balance = make(map[int]map[string]DayBalance)
+	var groups = make(map[int]map[string]map[string]int)
+	var index []int
 	for _, week := range m.Weeks {
-		month[week.Number] = make(map[string]int)
+		if _, found := month[week.Number]; !found {
+			month[week.Number] = make(map[string]int)
+			index = append(index, week.Number)
+		}
+		balance[week.Number] = week.Balance
+		groups[week.Number] = week.Groups
 		for _, day := range week.Days {
-			for _, dow := range daysOfWeek {
+			for _, dow := range m.Columns {
 				for _, t := range day[dow] {
 					month[week.Number][dow] += t
 				}
@@ -626,40 +495,31 @@ func (m *Month) print() {
 		}
 	}
 
-	for i := 0; i <= 92; i++ {
-		if i == 0 {
-			fmt.Printf(" ")
-		} else if i == 92 {
-			fmt.Printf("\n")
-		} else {
-			fmt.Printf("_")
+	// The first column fits the group names, indented under the week number.
+	var nameWidth = 10
+	for _, weekGroups := range groups {
+		for name := range weekGroups {
+			nameWidth = max(nameWidth, utf8.RuneCountInString(name)+1)
 		}
 	}
-
-	fmt.Printf("| %-10s ", "WK Number")
-	for _, title := range daysOfWeek {
-		fmt.Printf("| %-10s ", title)
-	}
-	fmt.Printf("| %-10s ", "WK Total(h)")
-	fmt.Printf("|\n")
-	for i := 0; i <= 91; i++ {
-		if i == 0 {
-			fmt.Printf("|")
-		} else if i == 13 || i == 26 || i == 39 || i == 52 || i == 65 || i == 78 {
-			fmt.Printf("|")
-		} else if i == 91 {
-			fmt.Printf("_|\n")
-		} else {
-			fmt.Printf("_")
-		}
+	var widths = []int{nameWidth + 2}
+	for range m.Columns {
+		widths = append(widths, 12)
 	}
-
-	var index []int
-	for key, _ := range month {
-		index = append(index, key)
+	widths = append(widths, 13)
+	var width = len(widths) + 1
+	for _, w := range widths {
+		width += w
 	}
 
-	sort.Ints(index)
+	printTableTop(out, widths)
+	fmt.Fprintf(out, "| %-*s ", nameWidth, "WK Number")
+	for _, title := range m.Columns {
+		fmt.Fprintf(out, "| %-10s ", title)
+	}
+	fmt.Fprintf(out, "| %-10s ", "WK Total(h)")
+	fmt.Fprintf(out, "|\n")
+	printTableRule(out, widths, "_")
 
 	var processedWeeks int
 	for _, i := range index {
@@ -667,45 +527,56 @@ func (m *Month) print() {
 		days := month[i]
 		var weekTotal = 0
 		if processedWeeks > 0 {
-			for i := 0; i <= 91; i++ {
-				if i == 0 {
-					fmt.Printf("|")
-				} else if i == 13 || i == 26 || i == 39 || i == 52 || i == 65 || i == 78 {
-					fmt.Printf("|")
-				} else if i == 91 {
-					fmt.Printf("_|\n")
-				} else {
-					fmt.Printf("_")
-				}
-			}
+			printTableRule(out, widths, "_")
 		}
-		fmt.Printf("| %-10d ", week)
-		for _, day := range daysOfWeek {
+		fmt.Fprintf(out, "| %-*d ", nameWidth, week)
+		for _, day := range m.Columns {
 			if days[day] == 0 {
-				fmt.Printf("| %-10s ", "")
+				fmt.Fprintf(out, "| %-10s ", "")
 			} else {
 				weekTotal += days[day]
-				fmt.Printf("| %-10.1f ", getInHours(days[day]))
+				fmt.Fprintf(out, "| %-10s ", Duration(days[day]).Text(m.Durations, "%.1f"))
 			}
 		}
-		fmt.Printf("| %-11.1f |\n", getInHours(weekTotal))
+		fmt.Fprintf(out, "| %-11s |\n", Duration(weekTotal).Text(m.Durations, "%.1f"))
+		printGroups(out, nameWidth, m.Columns, groups[i], m.Durations)
+		if len(balance[i]) > 0 {
+			printBalance(out, fmt.Sprintf("%%-%ds", nameWidth), m.Columns, balance[i], 11, false, m.Durations)
+		}
 		processedWeeks += 1
 	}
 
-	for i := 0; i <= 91; i++ {
-		if i == 0 {
-			fmt.Printf("|")
-		} else if i == 13 || i == 26 || i == 39 || i == 52 || i == 65 || i == 78 {
-			fmt.Printf("|")
-		} else if i == 91 {
-			fmt.Printf("_|\n")
-		} else {
-			fmt.Printf("_")
+	printTableRule(out, widths, "_")
+
+	fmt.Fprintln(out, fmt.Sprintf("%*s(h) | %-12s|", width-19, "Total", Duration(m.Total).Text(m.Durations, "%.1f")))
+	fmt.Fprintln(out, fmt.Sprintf("%*s |-------------|", width-16, ""))
+	fmt.Fprintln(out, fmt.Sprintf("%*s | %-12.1f|", width-16, "Days", float64(m.Total)/float64(m.SecondsInDay)))
+	fmt.Fprintln(out, fmt.Sprintf("%*s |-------------|", width-16, ""))
+	fmt.Fprintln(out, fmt.Sprintf("%*s(h) | %-12s|", width-19, "Expected", Duration(m.Expected).Text(m.Durations, "%.1f")))
+	fmt.Fprintln(out, fmt.Sprintf("%*s |-------------|", width-16, ""))
+	fmt.Fprintln(out, fmt.Sprintf("%*s(h) | %-12s|", width-19, "Difference", Duration(m.Total-m.Expected).Text(m.Durations, "%+.1f")))
+	fmt.Fprintln(out, fmt.Sprintf("%*s -------------", width-15, ""))
+}
+
+// printGroups writes a row for each group of a week of the month table, by name indented in a column of
+// nameWidth, with its time on each day and its sub-total.
+func printGroups(out io.Writer, nameWidth int, columns []string, groups map[string]map[string]int, durations string) {
+	var names []string
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		var total int
+		fmt.Fprintf(out, "|  %-*s ", nameWidth-1, name)
+		for _, day := range columns {
+			if seconds := groups[name][day]; seconds > 0 {
+				total += seconds
+				fmt.Fprintf(out, "| %-10s ", Duration(seconds).Text(durations, "%.1f"))
+			} else {
+				fmt.Fprintf(out, "| %-10s ", "")
+			}
 		}
+		fmt.Fprintf(out, "| %-11s |\n", Duration(total).Text(durations, "%.1f"))
 	}
-
-	fmt.Println(fmt.Sprintf("%74s(h) | %-12.1f|", "Total", getInHours(m.Total)))
-	fmt.Println(fmt.Sprintf("%77s |-------------|", ""))
-	fmt.Println(fmt.Sprintf("%77s | %-12.1f|", "Days", getInHours(m.Total)/8))
-	fmt.Println(fmt.Sprintf("%78s -------------", ""))
 }
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/praveenprem/timesheet/jira"
+)
+
+var errUnavailable = errors.New("worklogs unavailable")
+
+// poolAPI answers Worklogs after a delay shrinking with the issue number, so later issues finish first. The
+// issue named fail fails at once while the others wait for their context to be cancelled.
+type poolAPI struct {
+	jira.API
+	fail string
+
+	mu        sync.Mutex
+	running   int
+	peak      int
+	started   int
+	cancelled int
+}
+
+func (a *poolAPI) Worklogs(ctx context.Context, issueKey string, startedAfter time.Time, startedBefore time.Time) (*jira.WorkLogs, error) {
+	a.mu.Lock()
+	a.running++
+	a.started++
+	a.peak = max(a.peak, a.running)
+	a.mu.Unlock()
+	defer func() {
+		a.mu.Lock()
+		a.running--
+		a.mu.Unlock()
+	}()
+
+	var number, _ = strconv.Atoi(strings.TrimPrefix(issueKey, "DDSP-"))
+	switch {
+	case issueKey == a.fail:
+		return nil, errUnavailable
+	case a.fail != "":
+		<-ctx.Done()
+		a.mu.Lock()
+		a.cancelled++
+		a.mu.Unlock()
+		return nil, ctx.Err()
+	}
+	select {
+	case <-time.After(time.Duration(10-number) * time.Millisecond):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return &jira.WorkLogs{Total: number}, nil
+}
+
+func searchResult(count int) *jira.SearchResult {
+	var result jira.SearchResult
+	for i := 0; i < count; i++ {
+		var issue = jira.SearchIssue{Key: fmt.Sprintf("DDSP-%d", i)}
+		issue.Fields.Summary = fmt.Sprintf("Issue %d", i)
+		result.Issues = append(result.Issues, issue)
+	}
+	return &result
+}
+
+func TestGetWorklogsKeepsTheSearchOrder(t *testing.T) {
+	var api = &poolAPI{}
+	var app = &App{Client: api, Concurrency: 3}
+	var day = time.Date(2020, 3, 4, 0, 0, 0, 0, time.UTC)
+
+	worklogs, err := app.getWorklogs(context.Background(), searchResult(10), day, day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, worklog := range worklogs {
+		if worklog.Key != fmt.Sprintf("DDSP-%d", i) || worklog.Summary != fmt.Sprintf("Issue %d", i) || worklog.Total != i {
+			t.Errorf("worklogs[%d] = %+v", i, worklog)
+		}
+	}
+	if api.peak > 3 {
+		t.Errorf("%d requests ran at the same time, want at most 3", api.peak)
+	}
+}
+
+func TestGetWorklogsCancelsOnTheFirstError(t *testing.T) {
+	var api = &poolAPI{fail: "DDSP-1"}
+	var app = &App{Client: api, Concurrency: 3}
+	var day = time.Date(2020, 3, 4, 0, 0, 0, 0, time.UTC)
+
+	_, err := app.getWorklogs(context.Background(), searchResult(10), day, day)
+	if !errors.Is(err, errUnavailable) {
+		t.Fatalf("got %v, want the error of DDSP-1", err)
+	}
+	if api.cancelled == 0 || api.started == 10 {
+		t.Errorf("%d requests started and %d cancelled, want the requests in flight cancelled and no more started",
+			api.started, api.cancelled)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := app.getWorklogs(ctx, searchResult(3), day, day); !errors.Is(err, context.Canceled) {
+		t.Errorf("fetching with a cancelled context = %v, want context.Canceled", err)
+	}
+}
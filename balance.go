@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+/**
+ * Package name: main
+ * Project name: timesheet
+ * Created on: 18/10/2026 10:54
+ */
+
+// ReportBalance is the kind of report of the balance command.
+const ReportBalance = "balance"
+
+// WeekBalance is the time booked and expected in a week of the balance report, with the running balance at
+// its end.
+type WeekBalance struct {
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	Booked   int    `json:"booked_seconds"`
+	Expected int    `json:"expected_seconds"`
+	Delta    int    `json:"delta_seconds"`
+	Balance  int    `json:"balance_seconds"`
+}
+
+// GetBalance reports the flexi-time balance, the time booked less the time expected, from the balance start
+// of the profile up to the day of -d. The weeks are listed with their delta and the running balance, followed
+// by the time left to book for the balance to be back to zero on the last working day of the week.
+func (app *App) GetBalance(ctx context.Context) error {
+	if app.Configuration.BalanceStart.IsZero() {
+		return &ConfigError{Msg: "the balance needs a start date. set balance_start in the profile"}
+	}
+	day, err := time.Parse(YmdFormat, app.getDate())
+	if err != nil {
+		return err
+	}
+	var start = app.Configuration.BalanceStart
+	if day.Before(start) {
+		return usageErrorf("%s is before the balance start %s", day.Format(YmdFormat), start.Format(YmdFormat))
+	}
+	var weekStart, weekEnd = app.schedule().weekOf(day)
+
+	entries, err := app.collectEntries(ctx, start, weekEnd, func(started string) bool {
+		return app.isDateBetween(started, start, weekEnd)
+	})
+	if err != nil {
+		return err
+	}
+
+	var report = newReport(ReportBalance, start, weekEnd, entries)
+	report.DaySeconds = app.schedule().averageDay()
+	app.addBalance(report)
+	app.addWeekBalances(report, day)
+	report.BookBy = weekEnd.Format(YmdFormat)
+	for d := weekStart; !d.After(weekEnd); d = d.AddDate(0, 0, 1) {
+		if !d.Before(day) && app.schedule().secondsOn(d) > 0 {
+			report.BookBy = d.Format(YmdFormat)
+		}
+	}
+	return app.render(report)
+}
+
+// addWeekBalances sums the days of the report up to the given day by week, and works out what is left to book
+// in the rest of the week.
+func (app *App) addWeekBalances(report *Report, day time.Time) {
+	var today = day.Format(YmdFormat)
+	var balance = app.Configuration.OpeningBalance
+	var week *WeekBalance
+	var toBook = -balance
+
+	for _, d := range report.Days {
+		toBook += d.Expected - d.Booked
+		if d.Date > today {
+			continue
+		}
+		date, _ := time.Parse(YmdFormat, d.Date)
+		var weekStart, weekEnd = app.schedule().weekOf(date)
+		if week == nil || week.Start != weekStart.Format(YmdFormat) {
+			report.Weeks = append(report.Weeks, WeekBalance{Start: weekStart.Format(YmdFormat), End: weekEnd.Format(YmdFormat)})
+			week = &report.Weeks[len(report.Weeks)-1]
+		}
+		week.Booked += d.Booked
+		week.Expected += d.Expected
+		week.Delta += d.Difference
+		balance += d.Difference
+		week.Balance = balance
+	}
+
+	report.Balance = balance
+	report.ToBook = toBook
+	if report.ToBook < 0 {
+		report.ToBook = 0
+	}
+}
+
+// printWeekBalances writes the weeks of the balance report as a table.
+func printWeekBalances(w io.Writer, report *Report) {
+	fmt.Fprintf(w, "Balance since %s:\n", report.Start)
+	fmt.Fprintf(w, "%-12s %10s %10s %10s %10s\n", "Week", "Booked", "Expected", "Delta", "Balance")
+	for _, week := range report.Weeks {
+		fmt.Fprintf(w, "%-12s %10s %10s %10s %10s\n", week.Start, Duration(week.Booked).Text(report.durations, "%.2f"),
+			Duration(week.Expected).Text(report.durations, "%.2f"), Duration(week.Delta).Text(report.durations, "%+.2f"),
+			Duration(week.Balance).Text(report.durations, "%+.2f"))
+	}
+
+	switch {
+	case report.Balance > 0:
+		fmt.Fprintf(w, "You're %s ahead.", Duration(report.Balance).Text(report.durations, "%.2f hours"))
+	case report.Balance < 0:
+		fmt.Fprintf(w, "You're %s behind.", Duration(-report.Balance).Text(report.durations, "%.2f hours"))
+	default:
+		fmt.Fprintf(w, "You're even.")
+	}
+	fmt.Fprintf(w, " Book %s by %s to be back to zero.\n", Duration(report.ToBook).Text(report.durations, "%.2f hours"), report.BookBy)
+}
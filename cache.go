@@ -0,0 +1,420 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/praveenprem/timesheet/jira"
+)
+
+/**
+ * Package name: main
+ * Project name: timesheet
+ * Created on: 18/10/2026 10:46
+ */
+
+type (
+	// WorklogCache keeps the user's worklogs of a profile between runs, keyed by worklog ID, with the key and
+	// summary of their issues keyed by issue ID. Covered lists the days whose worklogs are all in the cache;
+	// changes made after Since are fetched from the worklog change feeds of Jira.
+	WorklogCache struct {
+		User     jira.User               `json:"user"`
+		Since    time.Time               `json:"since"`
+		Covered  []DateRange             `json:"covered"`
+		Issues   map[string]CachedIssue  `json:"issues"`
+		Worklogs map[string]jira.Worklog `json:"worklogs"`
+	}
+
+	// CachedIssue holds the fields of an issue reports are grouped by. Epic is the key of the parent issue and
+	// EpicSummary its summary.
+	CachedIssue struct {
+		Key         string   `json:"key"`
+		Summary     string   `json:"summary"`
+		Project     string   `json:"project"`
+		Epic        string   `json:"epic,omitempty"`
+		EpicSummary string   `json:"epic_summary,omitempty"`
+		Components  []string `json:"components,omitempty"`
+		Labels      []string `json:"labels,omitempty"`
+		Type        string   `json:"type,omitempty"`
+	}
+
+	// DateRange is a range of days, both ends included, in YYYY-MM-DD format.
+	DateRange struct {
+		Start string `json:"start"`
+		End   string `json:"end"`
+	}
+)
+
+func cachePath(profile string) (string, error) {
+	return cacheFile("worklogs", profile)
+}
+
+// cacheFile returns the location of a cache file of the profile, $XDG_CACHE_HOME/timesheet/<kind>-<profile>.json.
+func cacheFile(kind string, profile string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", &ConfigError{Msg: "unable to locate the cache directory", Err: err}
+	}
+	if profile == "" {
+		profile = "default"
+	}
+	return filepath.Join(dir, "timesheet", fmt.Sprintf("%s-%s.json", kind, profile)), nil
+}
+
+// readCache returns the worklog cache of the profile. A missing or unreadable cache is returned empty, to be
+// filled again from Jira.
+func readCache(profile string) (*WorklogCache, error) {
+	path, err := cachePath(profile)
+	if err != nil {
+		return nil, err
+	}
+	var cache WorklogCache
+	if data, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(data, &cache); err != nil {
+			cache = WorklogCache{}
+		}
+	}
+	if cache.Issues == nil {
+		cache.Issues = map[string]CachedIssue{}
+	}
+	if cache.Worklogs == nil {
+		cache.Worklogs = map[string]jira.Worklog{}
+	}
+	return &cache, nil
+}
+
+// writeCache saves the worklog cache of the profile, replacing the file in one step.
+func writeCache(profile string, cache *WorklogCache) error {
+	path, err := cachePath(profile)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data, 0600)
+}
+
+// covers tells whether every day from start to end is covered.
+func (c *WorklogCache) covers(start time.Time, end time.Time) bool {
+	var from, to = start.Format(YmdFormat), end.Format(YmdFormat)
+	for _, r := range c.Covered {
+		if r.Start <= from && to <= r.End {
+			return true
+		}
+	}
+	return false
+}
+
+// cover marks the days from start to end as covered, merging the ranges which overlap or touch.
+func (c *WorklogCache) cover(start time.Time, end time.Time) {
+	var ranges = append(c.Covered, DateRange{Start: start.Format(YmdFormat), End: end.Format(YmdFormat)})
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Start < ranges[j].Start })
+
+	var merged []DateRange
+	for _, r := range ranges {
+		if n := len(merged); n > 0 && r.Start <= nextDay(merged[n-1].End) {
+			if r.End > merged[n-1].End {
+				merged[n-1].End = r.End
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	c.Covered = merged
+}
+
+func nextDay(day string) string {
+	date, err := time.Parse(YmdFormat, day)
+	if err != nil {
+		return day
+	}
+	return date.AddDate(0, 0, 1).Format(YmdFormat)
+}
+
+// store keeps the worklog when it was booked by the user, and forgets it otherwise.
+func (c *WorklogCache) store(user *jira.User, log jira.Worklog) {
+	if !isAuthor(user, log) {
+		delete(c.Worklogs, log.Id)
+		return
+	}
+	c.Worklogs[log.Id] = log
+}
+
+// entries returns the cached worklogs accepted by match, in the order they were created, dated by dateOf.
+func (c *WorklogCache) entries(match func(started string) bool, dateOf func(started string) string) []ReportEntry {
+	var ids = make([]string, 0, len(c.Worklogs))
+	for id := range c.Worklogs {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, aErr := strconv.ParseInt(ids[i], 10, 64)
+		b, bErr := strconv.ParseInt(ids[j], 10, 64)
+		if aErr != nil || bErr != nil {
+			return ids[i] < ids[j]
+		}
+		return a < b
+	})
+
+	var entries []ReportEntry
+	for _, id := range ids {
+		var log = c.Worklogs[id]
+		issue, found := c.Issues[log.IssueId]
+		if !found || !match(log.Started) {
+			continue
+		}
+		entries = append(entries, ReportEntry{
+			Issue:       issue.Key,
+			Summary:     issue.Summary,
+			Project:     issue.Project,
+			Epic:        issue.Epic,
+			EpicSummary: issue.EpicSummary,
+			Components:  issue.Components,
+			Labels:      issue.Labels,
+			Type:        issue.Type,
+			Date:        dateOf(log.Started),
+			Started:     log.Started,
+			Seconds:     log.TimeSpentSeconds,
+			Comment:     log.Comment.Text(),
+			Author:      log.Author.DisplayName,
+		})
+	}
+	return entries
+}
+
+// isSameUser tells whether the cache was filled for the user.
+func (c *WorklogCache) isSameUser(user *jira.User) bool {
+	return c.User.AccountId == user.AccountId && c.User.Name == user.Name &&
+		strings.EqualFold(c.User.EmailAddress, user.EmailAddress)
+}
+
+// collectEntries returns the user's worklogs between start and end accepted by match from the cache,
+// bringing it up to date first unless -offline is given.
+func (app *App) collectEntries(ctx context.Context, start time.Time, end time.Time, match func(started string) bool) ([]ReportEntry, error) {
+	cache, err := readCache(app.Configuration.Profile)
+	if err != nil {
+		return nil, err
+	}
+
+	if app.Offline {
+		if cache.Since.IsZero() {
+			return nil, &ConfigError{Msg: "no worklogs are cached yet. run a report without -offline first"}
+		}
+		if !cache.covers(start, end) {
+			fmt.Fprintf(os.Stderr, "The cache doesn't hold every worklog from %s to %s, the report may be incomplete\n",
+				start.Format(YmdFormat), end.Format(YmdFormat))
+		}
+		app.User = &cache.User
+		return cache.entries(match, app.localDate), nil
+	}
+
+	user, err := app.currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !cache.isSameUser(user) {
+		cache = &WorklogCache{User: *user, Issues: map[string]CachedIssue{}, Worklogs: map[string]jira.Worklog{}}
+	}
+	if err := app.refreshCache(ctx, cache, start, end); err != nil {
+		return nil, err
+	}
+	if err := writeCache(app.Configuration.Profile, cache); err != nil {
+		fmt.Fprintln(os.Stderr, "unable to save the worklog cache:", err)
+	}
+	return cache.entries(match, app.localDate), nil
+}
+
+// refreshCache applies the changes made in Jira since the last refresh, then fetches the days from start to
+// end in full when they aren't covered yet. The change feeds list the worklogs of every user of the site by
+// ID alone, so only the cached worklogs among them are fetched by ID. Worklogs new to the cache are looked for
+// on the issues the user booked on since instead.
+func (app *App) refreshCache(ctx context.Context, cache *WorklogCache, start time.Time, end time.Time) error {
+	// stale holds the IDs of the issues whose worklogs changed, their fields are looked up again.
+	var stale = map[string]bool{}
+	if !cache.Since.IsZero() {
+		updated, until, err := app.Client.UpdatedWorklogs(ctx, cache.Since)
+		if err != nil {
+			return err
+		}
+		deleted, deletedUntil, err := app.Client.DeletedWorklogs(ctx, cache.Since)
+		if err != nil {
+			return err
+		}
+		if deletedUntil.Before(until) {
+			until = deletedUntil
+		}
+
+		var cached []int64
+		var added bool
+		for _, change := range updated {
+			if _, found := cache.Worklogs[strconv.FormatInt(change.WorklogId, 10)]; found {
+				cached = append(cached, change.WorklogId)
+			} else {
+				added = true
+			}
+		}
+		if len(cached) > 0 {
+			worklogs, err := app.Client.WorklogsById(ctx, cached)
+			if err != nil {
+				return err
+			}
+			for _, log := range worklogs {
+				cache.store(&cache.User, log)
+				stale[log.IssueId] = true
+			}
+		}
+		if added {
+			if err := app.fetchNewWorklogs(ctx, cache); err != nil {
+				return err
+			}
+		}
+		for _, change := range deleted {
+			delete(cache.Worklogs, strconv.FormatInt(change.WorklogId, 10))
+		}
+		cache.Since = until
+	}
+
+	if !cache.covers(start, end) {
+		var fetched = time.Now()
+		issues, err := getIssuesUpdatedBetweenDays(ctx, app.Client, start, end)
+		if err != nil {
+			return err
+		}
+		if err := app.cacheWorklogs(ctx, cache, issues, start, end); err != nil {
+			return err
+		}
+		cache.cover(start, end)
+		if cache.Since.IsZero() {
+			cache.Since = fetched
+		}
+	}
+
+	return app.cacheIssues(ctx, cache, stale)
+}
+
+// fetchNewWorklogs caches the worklogs on the issues the user booked on since the last refresh, within the
+// days the cache covers. Jira compares dates in the timezone of the user's Jira profile, so the search goes
+// back a day further.
+func (app *App) fetchNewWorklogs(ctx context.Context, cache *WorklogCache) error {
+	if len(cache.Covered) == 0 {
+		return nil
+	}
+	issues, err := app.Client.Search(ctx, fmt.Sprintf("worklogAuthor = currentUser() AND updated >= \"%s\"",
+		cache.Since.In(app.location()).AddDate(0, 0, -1).Format(YmdFormat)))
+	if err != nil {
+		return err
+	}
+	if len(issues.Issues) == 0 {
+		return nil
+	}
+	first, err := time.ParseInLocation(YmdFormat, cache.Covered[0].Start, app.location())
+	if err != nil {
+		return err
+	}
+	last, err := time.ParseInLocation(YmdFormat, cache.Covered[len(cache.Covered)-1].End, app.location())
+	if err != nil {
+		return err
+	}
+	return app.cacheWorklogs(ctx, cache, issues, first, last)
+}
+
+// cacheWorklogs caches the issues and the user's worklogs on them from start to end.
+func (app *App) cacheWorklogs(ctx context.Context, cache *WorklogCache, issues *jira.SearchResult, start time.Time, end time.Time) error {
+	worklogs, err := app.getWorklogs(ctx, issues, start, end)
+	if err != nil {
+		return err
+	}
+	for i, issue := range issues.Issues {
+		cache.Issues[issue.Id] = cachedIssue(issue)
+		for _, log := range worklogs[i].Worklogs {
+			log.IssueId = issue.Id
+			cache.store(&cache.User, log)
+		}
+	}
+	return nil
+}
+
+// cachedIssue keeps the fields of a search result reports use.
+func cachedIssue(issue jira.SearchIssue) CachedIssue {
+	var cached = CachedIssue{Key: issue.Key, Summary: issue.Fields.Summary, Labels: issue.Fields.Labels}
+	if issue.Fields.Project != nil {
+		cached.Project = issue.Fields.Project.Key
+	}
+	if issue.Fields.Parent != nil {
+		cached.Epic = issue.Fields.Parent.Key
+		cached.EpicSummary = issue.Fields.Parent.Fields.Summary
+	}
+	for _, component := range issue.Fields.Components {
+		cached.Components = append(cached.Components, component.Name)
+	}
+	if issue.Fields.IssueType != nil {
+		cached.Type = issue.Fields.IssueType.Name
+	}
+	return cached
+}
+
+// cacheIssues looks up the fields of the issues of cached worklogs which aren't known yet, e.g. worklogs
+// booked by this program, or were cached without their project by an older version, and of the stale
+// issues whose worklogs changed. The fields of other issues, e.g. one renamed since, are kept as cached.
+func (app *App) cacheIssues(ctx context.Context, cache *WorklogCache, stale map[string]bool) error {
+	var missing = map[string]bool{}
+	for _, log := range cache.Worklogs {
+		if issue, found := cache.Issues[log.IssueId]; (!found || issue.Project == "" || stale[log.IssueId]) && log.IssueId != "" {
+			missing[log.IssueId] = true
+		}
+	}
+	var ids []string
+	for id := range missing {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for start := 0; start < len(ids); start += 100 {
+		var end = start + 100
+		if end > len(ids) {
+			end = len(ids)
+		}
+		issues, err := app.Client.Search(ctx, fmt.Sprintf("id in (%s)", strings.Join(ids[start:end], ", ")))
+		if err != nil {
+			return err
+		}
+		for _, issue := range issues.Issues {
+			cache.Issues[issue.Id] = cachedIssue(issue)
+		}
+	}
+	return nil
+}
+
+// rememberWorklog puts a worklog booked or changed by this run in the cache, as Jira leaves the changes of
+// the last minute out of its feeds. It does nothing before the cache was filled once.
+func (app *App) rememberWorklog(log *jira.Worklog) {
+	app.changeCache(func(cache *WorklogCache) {
+		cache.store(&cache.User, *log)
+	})
+}
+
+// forgetWorklog removes a worklog deleted by this run from the cache.
+func (app *App) forgetWorklog(id string) {
+	app.changeCache(func(cache *WorklogCache) {
+		delete(cache.Worklogs, id)
+	})
+}
+
+func (app *App) changeCache(change func(cache *WorklogCache)) {
+	cache, err := readCache(app.Configuration.Profile)
+	if err != nil || cache.Since.IsZero() {
+		return
+	}
+	change(cache)
+	if err := writeCache(app.Configuration.Profile, cache); err != nil {
+		fmt.Fprintln(os.Stderr, "unable to save the worklog cache:", err)
+	}
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/**
+ * Package name: main
+ * Project name: timesheet
+ * Created on: 18/10/2026 10:50
+ */
+
+type (
+	// Absence is a holiday or a leave on a day. Seconds is the time off, zero for the whole day.
+	Absence struct {
+		Date    string
+		Seconds int
+		Name    string
+	}
+
+	// Calendar holds the absences of the user by day, in YYYY-MM-DD format.
+	Calendar map[string][]Absence
+)
+
+// loadCalendars reads the holiday and leave calendars of a profile, iCalendar files (.ics) or YAML lists
+// (.yaml, .yml).
+func loadCalendars(paths []string) (Calendar, error) {
+	var calendar = make(Calendar)
+	for _, path := range paths {
+		if strings.HasPrefix(path, "~/") {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil, err
+			}
+			path = filepath.Join(home, path[2:])
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+
+		var absences []Absence
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".ics":
+			absences, err = parseICalendar(file)
+		case ".yaml", ".yml":
+			absences, err = parseAbsenceList(file)
+		default:
+			err = fmt.Errorf("unknown calendar type, expected .ics, .yaml or .yml")
+		}
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		for _, absence := range absences {
+			calendar[absence.Date] = append(calendar[absence.Date], absence)
+		}
+	}
+	return calendar, nil
+}
+
+// timeOff returns the time off on the date out of the seconds expected that day.
+func (c Calendar) timeOff(date time.Time, expected int) int {
+	var off int
+	for _, absence := range c[date.Format(YmdFormat)] {
+		if absence.Seconds == 0 {
+			return expected
+		}
+		off += absence.Seconds
+	}
+	if off > expected {
+		return expected
+	}
+	return off
+}
+
+// expectedOn returns the time expected on the date by the schedule less the time off in the calendars, with
+// the names of the holidays and leave of the day.
+func (app *App) expectedOn(date time.Time) (int, string) {
+	var expected = app.schedule().secondsOn(date)
+	var names []string
+	for _, absence := range app.Configuration.Calendar[date.Format(YmdFormat)] {
+		if absence.Name != "" {
+			names = append(names, absence.Name)
+		}
+	}
+	return expected - app.Configuration.Calendar.timeOff(date, expected), strings.Join(names, ", ")
+}
+
+// parseICalendar reads the events of an iCalendar file. All-day events are whole days off, from DTSTART up to
+// the day before DTEND. Events with a time of day take their duration off the day they start on, in the time
+// zone given by TZID when there is one. RRULE is ignored, so recurring events only count on their first day.
+func parseICalendar(r io.Reader) ([]Absence, error) {
+	var lines []string
+	var scanner = bufio.NewScanner(r)
+	for scanner.Scan() {
+		var line = strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var absences []Absence
+	var event map[string]string
+	for n, line := range lines {
+		switch {
+		case line == "BEGIN:VEVENT":
+			event = make(map[string]string)
+		case line == "END:VEVENT" && event != nil:
+			found, err := eventAbsences(event)
+			if err != nil {
+				return nil, fmt.Errorf("event ending on line %d: %v", n+1, err)
+			}
+			absences = append(absences, found...)
+			event = nil
+		case event != nil:
+			var parts = strings.SplitN(line, ":", 2)
+			if len(parts) == 2 {
+				var params = strings.Split(parts[0], ";")
+				var name = strings.ToUpper(params[0])
+				event[name] = parts[1]
+				for _, param := range params[1:] {
+					if strings.HasPrefix(strings.ToUpper(param), "TZID=") {
+						event[name+";TZID"] = strings.Trim(param[len("TZID="):], `"`)
+					}
+				}
+			}
+		}
+	}
+	return absences, nil
+}
+
+func eventAbsences(event map[string]string) ([]Absence, error) {
+	var name = strings.NewReplacer(`\,`, ",", `\;`, ";", `\n`, " ").Replace(event["SUMMARY"])
+	start, allDay, err := parseICalendarTime(event["DTSTART"], event["DTSTART;TZID"])
+	if err != nil {
+		return nil, err
+	}
+
+	if allDay {
+		var end = start.AddDate(0, 0, 1)
+		if event["DTEND"] != "" {
+			if end, _, err = parseICalendarTime(event["DTEND"], event["DTEND;TZID"]); err != nil {
+				return nil, err
+			}
+		}
+		var absences []Absence
+		for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+			absences = append(absences, Absence{Date: day.Format(YmdFormat), Name: name})
+		}
+		return absences, nil
+	}
+
+	end, _, err := parseICalendarTime(event["DTEND"], event["DTEND;TZID"])
+	if err != nil {
+		return nil, err
+	}
+	if !end.After(start) {
+		return nil, fmt.Errorf("event %q has no valid end", name)
+	}
+	return []Absence{{Date: start.Format(YmdFormat), Seconds: int(end.Sub(start).Seconds()), Name: name}}, nil
+}
+
+// parseICalendarTime reads a DATE or DATE-TIME value and tells whether it was a date alone. A local time is read
+// in the IANA time zone tzid, e.g. Europe/London, when it is given.
+func parseICalendarTime(value string, tzid string) (time.Time, bool, error) {
+	if date, err := time.Parse("20060102", value); err == nil {
+		return date, true, nil
+	}
+	if date, err := time.Parse("20060102T150405Z", value); err == nil {
+		return date, false, nil
+	}
+	var location = time.UTC
+	if tzid != "" {
+		var err error
+		if location, err = time.LoadLocation(tzid); err != nil {
+			return time.Time{}, false, fmt.Errorf("unknown time zone %q of %s, only IANA names are supported", tzid, value)
+		}
+	}
+	if date, err := time.ParseInLocation("20060102T150405", value, location); err == nil {
+		return date, false, nil
+	}
+	return time.Time{}, false, fmt.Errorf("invalid date %q", value)
+}
+
+// parseAbsenceList reads a YAML list of absences, each a whole day (date), a range of whole days (from and
+// to) or part of a day (date and hours off), with an optional name:
+//
+//   - date: 2020-12-25
+//     name: Christmas Day
+//   - date: 2020-12-24
+//     hours: 4
+//   - from: 2020-08-03
+//     to: 2020-08-14
+//     name: Summer leave
+func parseAbsenceList(r io.Reader) ([]Absence, error) {
+	var items []map[string]string
+	var scanner = bufio.NewScanner(r)
+	var n int
+	for scanner.Scan() {
+		n++
+		var line = scanner.Text()
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = line[:i]
+		}
+		var trimmed = strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || trimmed == "---" {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "- ") {
+			items = append(items, make(map[string]string))
+			trimmed = strings.TrimSpace(trimmed[2:])
+		}
+		var parts = strings.SplitN(trimmed, ":", 2)
+		if len(items) == 0 || len(parts) != 2 {
+			return nil, fmt.Errorf("line %d: expected a list of date, from, to, hours and name fields", n)
+		}
+		var value = strings.Trim(strings.TrimSpace(parts[1]), `"'`)
+		items[len(items)-1][strings.TrimSpace(parts[0])] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var absences []Absence
+	for i, item := range items {
+		var from, to = item["from"], item["to"]
+		if item["date"] != "" {
+			from, to = item["date"], item["date"]
+		}
+		start, err := time.Parse(YmdFormat, from)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: invalid date %q", i+1, from)
+		}
+		end, err := time.Parse(YmdFormat, to)
+		if err != nil || end.Before(start) {
+			return nil, fmt.Errorf("entry %d: invalid end date %q", i+1, to)
+		}
+
+		var seconds int
+		if item["hours"] != "" {
+			hours, err := strconv.ParseFloat(item["hours"], 64)
+			if err != nil || hours <= 0 || hours > 24 {
+				return nil, fmt.Errorf("entry %d: invalid hours %q", i+1, item["hours"])
+			}
+			seconds = int(hours * 3600)
+		}
+		for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+			absences = append(absences, Absence{Date: day.Format(YmdFormat), Seconds: seconds, Name: item["name"]})
+		}
+	}
+	return absences, nil
+}
@@ -0,0 +1,412 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/praveenprem/timesheet/jira"
+)
+
+/**
+ * Package name: main
+ * Project name: timesheet
+ * Created on: 18/10/2026 11:05
+ */
+
+// Setup levels, what run prepares before running a command.
+const (
+	// setupNone runs the command straight away, e.g. the timer keeps its state locally.
+	setupNone = iota
+	// setupConfig loads the profile and creates the Jira client.
+	setupConfig
+	// setupReport also checks for a newer release, unless -offline is given.
+	setupReport
+)
+
+// Command is a command of the command line with its own flags, help and validation. Args is the synopsis of
+// its arguments and Validate checks them, commands without Validate taking none. Hidden commands are left out
+// of the help, and the flags of RawArgs commands are not parsed but passed on as arguments.
+type Command struct {
+	Name     string
+	Args     string
+	Summary  string
+	Examples []string
+	Setup    int
+	Hidden   bool
+	RawArgs  bool
+	Flags    func(app *App, flags *flag.FlagSet)
+	Validate func(app *App) error
+	Run      func(app *App, ctx context.Context) error
+}
+
+// commands lists the commands in the order of the help.
+var commands = []*Command{
+	{
+		Name:    "log",
+		Summary: "Book time on an issue and print the time left to book that day",
+		Examples: []string{
+			`log -r DDSP-XXXX -t 8h -m "Jenkins pipeline completed"`,
+			`log -r DDSP-XXXX -t 1h -m "Investigated possible solutions" -d 2020-03-05`,
+		},
+		// Bookings skip the release check, which would hold them up when offline before they can be queued.
+		Setup: setupConfig,
+		Flags: func(app *App, flags *flag.FlagSet) {
+			app.worklogFlags(flags, "REQUIRED: ")
+			app.connectionFlags(flags)
+		},
+		Validate: (*App).validateLog,
+		Run: func(app *App, ctx context.Context) error {
+			booked, err := app.bookTime(ctx, app.Ticket, app.Spent, app.Started, app.Comment)
+			if err != nil || !booked {
+				return err
+			}
+			return app.GetTimeRemaining(ctx)
+		},
+	},
+	{
+		Name:     "remaining",
+		Summary:  "Print how many hours are left to book on the day",
+		Examples: []string{"remaining", "remaining -d 2020-03-05"},
+		Setup:    setupReport,
+		Flags:    (*App).reportFlags,
+		Validate: (*App).validateReport,
+		Run:      (*App).GetTimeRemaining,
+	},
+	{
+		Name:     "day",
+		Summary:  "Print the timesheet of the day",
+		Examples: []string{"day", "day -d -1"},
+		Setup:    setupReport,
+		Flags:    (*App).reportFlags,
+		Validate: (*App).validateReport,
+		Run:      (*App).GetHistory,
+	},
+	{
+		Name:     "week",
+		Summary:  "Print the timesheet of the week",
+		Examples: []string{"week -offline", "week -group-by project"},
+		Setup:    setupReport,
+		Flags: func(app *App, flags *flag.FlagSet) {
+			app.reportFlags(flags)
+			app.groupByFlag(flags)
+		},
+		Validate: (*App).validateReport,
+		Run:      (*App).GetWeekTimesheet,
+	},
+	{
+		Name:     "month",
+		Summary:  "Print the timesheet of the month",
+		Examples: []string{"month -format csv > timesheet.csv", "month -group-by epic"},
+		Setup:    setupReport,
+		Flags: func(app *App, flags *flag.FlagSet) {
+			app.reportFlags(flags)
+			app.groupByFlag(flags)
+		},
+		Validate: (*App).validateReport,
+		Run:      (*App).GetMonthTimesheet,
+	},
+	{
+		Name:    "range",
+		Args:    "[PERIOD]",
+		Summary: "Print the time booked over a period or between two dates",
+		Examples: []string{
+			"range last-quarter -group-by project -format csv",
+			"range -from 2020-01-06 -to 2020-02-02 -interval week",
+		},
+		Setup: setupConfig,
+		Flags: func(app *App, flags *flag.FlagSet) {
+			app.reportFlags(flags)
+			app.groupByFlag(flags)
+			flags.StringVar(&app.From, "from", "",
+				"First day, as YYYY-MM-DD, of the report when no period is given")
+			flags.StringVar(&app.To, "to", "",
+				"Last day, as YYYY-MM-DD, of the report. Defaults to the day of -d")
+			flags.StringVar(&app.Interval, "interval", "month",
+				fmt.Sprintf("How the report sums the time. One of: %s", strings.Join(rangeIntervals, ", ")))
+		},
+		Validate: (*App).validateRange,
+		Run:      (*App).GetRange,
+	},
+	{
+		Name:     "balance",
+		Summary:  "Print the running flexi-time balance since the profile's balance_start",
+		Examples: []string{"balance"},
+		Setup:    setupConfig,
+		Flags:    (*App).reportFlags,
+		Validate: (*App).validateReport,
+		Run:      (*App).GetBalance,
+	},
+	{
+		Name:     "list",
+		Summary:  "List your worklogs of the day with their IDs",
+		Examples: []string{"list -d -1", "list -r DDSP-XXXX"},
+		Setup:    setupReport,
+		Flags: func(app *App, flags *flag.FlagSet) {
+			app.dateFlag(flags)
+			flags.StringVar(&app.Ticket, "r", "", "Limit the list to the worklogs of this issue. E.g. DDSP-4")
+			app.connectionFlags(flags)
+		},
+		Run: (*App).ListWorklogs,
+	},
+	{
+		Name:     "edit",
+		Args:     "ID",
+		Summary:  "Update a worklog of an issue with the given -t, -d, -at and -m",
+		Examples: []string{"edit 10042 -r DDSP-XXXX -t 4h"},
+		Setup:    setupReport,
+		Flags: func(app *App, flags *flag.FlagSet) {
+			app.worklogFlags(flags, "")
+			app.changeFlags(flags)
+			flags.StringVar(&app.NewEstimate, "new-estimate", "",
+				"Remaining estimate to set when -estimate is new. E.g. 2d")
+			app.connectionFlags(flags)
+		},
+		Validate: (*App).validateWorklogChange,
+		Run:      (*App).EditWorklog,
+	},
+	{
+		Name:     "delete",
+		Args:     "ID",
+		Summary:  "Delete a worklog of an issue",
+		Examples: []string{"delete 10042 -r DDSP-XXXX -estimate leave"},
+		Setup:    setupReport,
+		Flags: func(app *App, flags *flag.FlagSet) {
+			flags.StringVar(&app.Ticket, "r", "", "REQUIRED: Jira ticket reference of the worklog. E.g. DDSP-4")
+			app.changeFlags(flags)
+			flags.StringVar(&app.NewEstimate, "new-estimate", "",
+				"Remaining estimate to set when -estimate is new. E.g. 2d")
+			flags.StringVar(&app.IncreaseBy, "increase-by", "",
+				"Amount to increase the remaining estimate by when -estimate is manual. E.g. 4h")
+			app.connectionFlags(flags)
+		},
+		Validate: (*App).validateWorklogChange,
+		Run:      (*App).DeleteWorklog,
+	},
+	{
+		Name:     "start",
+		Args:     "TICKET",
+		Summary:  "Start timing work on an issue",
+		Examples: []string{`start DDSP-XXXX -m "Debugging the pipeline"`},
+		Flags: func(app *App, flags *flag.FlagSet) {
+			flags.StringVar(&app.Comment, "m", "", "A comment about the worklog")
+			app.profileFlag(flags)
+		},
+		Validate: (*App).validateTimer,
+		Run:      (*App).RunTimer,
+	},
+	{
+		Name:     "stop",
+		Summary:  "Stop the timer and book the elapsed time",
+		Examples: []string{"stop -round nearest:15"},
+		Flags: func(app *App, flags *flag.FlagSet) {
+			app.roundFlag(flags)
+			app.profileFlag(flags)
+		},
+		Validate: (*App).validateTimer,
+		Run:      (*App).RunTimer,
+	},
+	{
+		Name:     "status",
+		Summary:  "Show the running timer",
+		Validate: (*App).validateTimer,
+		Run:      (*App).RunTimer,
+	},
+	{
+		Name:     "switch",
+		Args:     "TICKET",
+		Summary:  "Stop the timer, book the elapsed time and start timing another issue",
+		Examples: []string{`switch DDSP-YYYY -m "Code review"`},
+		Flags: func(app *App, flags *flag.FlagSet) {
+			flags.StringVar(&app.Comment, "m", "", "A comment about the new worklog")
+			app.roundFlag(flags)
+			app.profileFlag(flags)
+		},
+		Validate: (*App).validateTimer,
+		Run:      (*App).RunTimer,
+	},
+	{
+		Name:     "queue",
+		Args:     "[list | edit N | drop N]",
+		Summary:  "List, change or drop the bookings waiting for the network",
+		Examples: []string{"queue", "queue edit 2 -t 45m", "queue drop 2 -yes"},
+		Flags: func(app *App, flags *flag.FlagSet) {
+			app.worklogFlags(flags, "")
+			flags.BoolVar(&app.Yes, "yes", false, "Don't ask for confirmation before dropping a booking")
+		},
+		Validate: (*App).validateQueue,
+		Run: func(app *App, ctx context.Context) error {
+			return app.RunQueue()
+		},
+	},
+	{
+		Name:     "sync",
+		Summary:  "Send the bookings made while Jira couldn't be reached",
+		Examples: []string{"sync"},
+		Setup:    setupConfig,
+		Flags:    (*App).profileFlag,
+		Validate: (*App).validateQueue,
+		Run:      (*App).SyncQueue,
+	},
+	{
+		Name:    "config",
+		Args:    "(login | encode CREDENTIALS)",
+		Summary: "Save a profile with the login flow, or base64 encode credentials",
+		Examples: []string{
+			"config login -profile internal -backend keyring",
+			"config encode 'example@example.com:abcThisIsFake;xyz.atlassian.net'",
+		},
+		Flags: func(app *App, flags *flag.FlagSet) {
+			app.profileFlag(flags)
+			flags.StringVar(&app.Backend, "backend", "keyring",
+				fmt.Sprintf("Secret store login keeps the API token in. One of: %s", strings.Join(credentialBackends, ", ")))
+		},
+		Validate: (*App).validateConfig,
+		Run: func(app *App, ctx context.Context) error {
+			if app.Args[0] == "login" {
+				return app.CredentialLogin(os.Stdin, app.out())
+			}
+			app.CredentialEncode()
+			return nil
+		},
+	},
+	{
+		Name:    "completion",
+		Args:    "SHELL",
+		Summary: "Print the completion script of bash, zsh or fish",
+		Examples: []string{
+			"completion bash > ~/.local/share/bash-completion/completions/timesheet",
+			`completion zsh > "${fpath[1]}/_timesheet"`,
+			"completion fish > ~/.config/fish/completions/timesheet.fish",
+		},
+		Validate: (*App).validateCompletion,
+		Run:      (*App).PrintCompletion,
+	},
+	{
+		Name:     "__complete",
+		Args:     "WORD...",
+		Summary:  "Print the completions of the last word of a command line",
+		Hidden:   true,
+		RawArgs:  true,
+		Validate: func(app *App) error { return nil },
+	},
+	{
+		Name:    "__assigned",
+		Summary: "Search the open issues assigned to you for the completion",
+		Setup:   setupConfig,
+		Hidden:  true,
+		Flags:   (*App).profileFlag,
+		Run:     (*App).RefreshAssigned,
+	},
+	{
+		Name:    "version",
+		Summary: "Print the version",
+		Run: func(app *App, ctx context.Context) error {
+			fmt.Fprint(app.out(), SIGNATURE)
+			fmt.Fprintln(app.out(), "Version:", VERSION)
+			return nil
+		},
+	},
+	{
+		Name:     "help",
+		Args:     "[COMMAND]",
+		Summary:  "Print the commands, or the flags and examples of a command",
+		Examples: []string{"help week"},
+	},
+}
+
+func init() {
+	// help and __complete look the commands up, so they are wired here to keep the table free of an
+	// initialisation cycle.
+	var help = findCommand("help")
+	help.Validate, help.Run = (*App).validateHelp, (*App).printHelp
+	findCommand("__complete").Run = (*App).Complete
+}
+
+// printHelp prints the commands, or the help of the command given.
+func (app *App) printHelp(ctx context.Context) error {
+	if len(app.Args) == 0 {
+		printUsage(app.out())
+		return nil
+	}
+	var command = findCommand(app.Args[0])
+	var flags = flag.NewFlagSet("timesheet "+command.Name, flag.ContinueOnError)
+	if command.Flags != nil {
+		command.Flags(&App{}, flags)
+	}
+	command.help(app.out(), flags)
+	return nil
+}
+
+// findCommand returns the command of the name, nil when there is none.
+func findCommand(name string) *Command {
+	for _, command := range commands {
+		if command.Name == name {
+			return command
+		}
+	}
+	return nil
+}
+
+// worklogFlags registers the flags describing a worklog, required by log when required is "REQUIRED: ".
+func (app *App) worklogFlags(flags *flag.FlagSet, required string) {
+	flags.StringVar(&app.Ticket, "r", "",
+		required+"Jira ticket reference. E.g. DDSP-4")
+	flags.StringVar(&app.TimeSpent, "t", "",
+		required+"The time spent as weeks (#w), days (#d) of the profile's daily_hours, hours (#h) and minutes (#m or a lone #), or as a clock (h:mm). E.g. 1h 30m, 1.5h, 90m or 1:30")
+	app.dateFlag(flags)
+	flags.StringVar(&app.At, "at", "",
+		"The time of day, as hh:mm, the worklog was started at on the day of -d. Defaults to 09:00 when -d is given and now otherwise. E.g. 13:30")
+	flags.StringVar(&app.Comment, "m", "",
+		"A comment about the worklog")
+}
+
+// dateFlag registers -d, the day the worklog was started on or the report is for.
+func (app *App) dateFlag(flags *flag.FlagSet) {
+	flags.StringVar(&app.Date, "d", "",
+		"The day the worklog was started on, or the report is for. Defaults to today. One of today, yesterday, a day"+
+			" of this week (mon) or the last (last fri), days (-1), weeks (-1w) or working days (-2wd) from today,"+
+			" or a full date (YYYY-MM-DD), optionally with the start time (\"YYYY-MM-DD hh:mm\")")
+}
+
+// reportFlags registers the flags shared by the reports.
+func (app *App) reportFlags(flags *flag.FlagSet) {
+	app.dateFlag(flags)
+	flags.StringVar(&app.Format, "format", "table",
+		fmt.Sprintf("Output format. One of: %s", strings.Join(reportFormats, ", ")))
+	flags.BoolVar(&app.Offline, "offline", false,
+		"Render the report from the local worklog cache without contacting Jira")
+	app.connectionFlags(flags)
+}
+
+func (app *App) groupByFlag(flags *flag.FlagSet) {
+	flags.StringVar(&app.GroupBy, "group-by", "",
+		fmt.Sprintf("What the time is summed by, with sub-totals. One of: %s", strings.Join(reportGroups, ", ")))
+}
+
+// changeFlags registers the flags of edit and delete.
+func (app *App) changeFlags(flags *flag.FlagSet) {
+	flags.BoolVar(&app.Yes, "yes", false,
+		"Don't ask for confirmation before changing the worklog")
+	flags.StringVar(&app.Estimate, "estimate", "auto",
+		fmt.Sprintf("How the remaining estimate is adjusted. One of: %s", strings.Join(jira.EstimateModes, ", ")))
+}
+
+func (app *App) roundFlag(flags *flag.FlagSet) {
+	flags.StringVar(&app.Round, "round", "",
+		"How the elapsed time is rounded, as mode:minutes with mode up, down or nearest, or off. E.g. nearest:15")
+}
+
+func (app *App) profileFlag(flags *flag.FlagSet) {
+	flags.StringVar(&app.Profile, "profile", "",
+		"Name of the configuration profile to use. Defaults to TIMESHEET_PROFILE or the default profile")
+}
+
+// connectionFlags registers the flags of the commands talking to Jira.
+func (app *App) connectionFlags(flags *flag.FlagSet) {
+	app.profileFlag(flags)
+	flags.IntVar(&app.Concurrency, "concurrency", 4,
+		"Maximum number of issues to fetch worklogs for at the same time")
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/praveenprem/timesheet/jira"
+)
+
+/**
+ * Package name: main
+ * Project name: timesheet
+ * Created on: 18/10/2026 11:08
+ */
+
+// completionShells lists the shells the completion command writes scripts for.
+var completionShells = []string{"bash", "zsh", "fish"}
+
+// completionScripts hand the words of the command line to __complete, which answers with one completion per
+// line, optionally followed by a tab and its description.
+var completionScripts = map[string]string{
+	"bash": `# bash completion for timesheet
+_timesheet() {
+    local IFS=$'\n'
+    COMPREPLY=($(timesheet __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+    COMPREPLY=("${COMPREPLY[@]%%$'\t'*}")
+}
+complete -F _timesheet timesheet
+`,
+	"zsh": `#compdef timesheet
+# zsh completion for timesheet
+_timesheet() {
+    local -a completions
+    local line
+    for line in "${(@f)$(timesheet __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}"; do
+        [[ -z $line ]] && continue
+        if [[ $line == *$'\t'* ]]; then
+            completions+=("${${line%%$'\t'*}//:/\\:}:${line#*$'\t'}")
+        else
+            completions+=("${line//:/\\:}")
+        fi
+    done
+    _describe timesheet completions
+}
+if [[ $funcstack[1] == _timesheet ]]; then
+    _timesheet "$@"
+else
+    compdef _timesheet timesheet
+fi
+`,
+	"fish": `# fish completion for timesheet
+function __timesheet_complete
+    set -l words (commandline -opc)
+    set -e words[1]
+    timesheet __complete $words (commandline -ct) 2>/dev/null
+end
+complete -c timesheet -f -a '(__timesheet_complete)'
+`,
+}
+
+// PrintCompletion writes the completion script of the shell given.
+func (app *App) PrintCompletion(ctx context.Context) error {
+	fmt.Fprint(app.out(), completionScripts[app.Args[0]])
+	return nil
+}
+
+// Complete prints the completions of the last of the words given, the command line after the program name.
+// It only reads local files, so it answers while the shell waits, and leaves searching the assigned issues
+// once they are stale to __assigned in the background.
+func (app *App) Complete(ctx context.Context) error {
+	for _, completion := range complete(app.Args) {
+		fmt.Fprintln(app.out(), completion)
+	}
+	refreshInBackground(completionProfile(app.Args))
+	return nil
+}
+
+// refreshInBackground starts __assigned for the profile, without waiting for it, when its assigned issues are
+// older than assignedMaxAge and no refresh was started in the last assignedRetry.
+func refreshInBackground(profile string) {
+	assigned, err := readAssigned(profile)
+	if err != nil || time.Since(assigned.Fetched) < assignedMaxAge || time.Since(assigned.Requested) < assignedRetry {
+		return
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return
+	}
+	var args = []string{"__assigned"}
+	if profile != "" {
+		args = append(args, "-profile", profile)
+	}
+	var cmd = exec.Command(executable, args...)
+	if err := cmd.Start(); err != nil {
+		return
+	}
+	_ = cmd.Process.Release()
+	assigned.Requested = time.Now()
+	_ = writeAssigned(profile, assigned)
+}
+
+// complete returns the commands, flags, flag values or arguments starting with the last word, as the value
+// and its description separated by a tab. A line in the legacy form, e.g. -r DDSP-4 -t, is completed as the
+// command it stands for.
+func complete(words []string) []string {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	var current, before = words[len(words)-1], words[:len(words)-1]
+	if len(before) == 0 {
+		return matching(current, commandCompletions())
+	}
+	if strings.HasPrefix(before[0], "-") {
+		translated, err := legacyArgs(before)
+		if err != nil {
+			return nil
+		}
+		before = translated
+		words = append(append([]string(nil), translated...), current)
+	}
+	var command = findCommand(before[0])
+	if command == nil || command.Hidden {
+		return nil
+	}
+
+	var flags = flag.NewFlagSet(command.Name, flag.ContinueOnError)
+	if command.Flags != nil {
+		command.Flags(&App{}, flags)
+	}
+	var position int
+	for i := 1; i < len(before); i++ {
+		var name = strings.TrimLeft(before[i], "-")
+		if !strings.HasPrefix(before[i], "-") || name == "" {
+			position++
+			continue
+		}
+		if strings.Contains(name, "=") {
+			continue
+		}
+		if f := flags.Lookup(name); f != nil && !isBoolFlag(f) {
+			if i == len(before)-1 {
+				return matching(current, flagValueCompletions(name, words))
+			}
+			i++
+		}
+	}
+
+	if strings.HasPrefix(current, "-") {
+		var completions []string
+		flags.VisitAll(func(f *flag.Flag) {
+			completions = append(completions, fmt.Sprintf("-%s\t%s", f.Name, f.Usage))
+		})
+		return matching(current, completions)
+	}
+	return matching(current, argumentCompletions(command.Name, position, words))
+}
+
+// matching keeps the completions starting with prefix, ignoring case so ddsp completes to DDSP-4.
+func matching(prefix string, completions []string) []string {
+	var matches []string
+	for _, completion := range completions {
+		if strings.HasPrefix(strings.ToLower(completion), strings.ToLower(prefix)) {
+			matches = append(matches, completion)
+		}
+	}
+	return matches
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	boolFlag, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && boolFlag.IsBoolFlag()
+}
+
+func commandCompletions() []string {
+	var completions []string
+	for _, command := range commands {
+		if !command.Hidden {
+			completions = append(completions, command.Name+"\t"+command.Summary)
+		}
+	}
+	return completions
+}
+
+// flagValueCompletions returns the values the flag accepts, when there is a known set of them.
+func flagValueCompletions(name string, words []string) []string {
+	switch name {
+	case "r":
+		return ticketCompletions(words)
+	case "format":
+		return reportFormats
+	case "group-by":
+		return reportGroups
+	case "interval":
+		return rangeIntervals
+	case "estimate":
+		return jira.EstimateModes
+	case "backend":
+		return credentialBackends
+	case "profile":
+		if config := completionConfig(); config != nil {
+			return config.profileNames()
+		}
+	}
+	return nil
+}
+
+// argumentCompletions returns the values the argument at position of the command accepts.
+func argumentCompletions(command string, position int, words []string) []string {
+	if position > 0 {
+		return nil
+	}
+	switch command {
+	case "start", "switch":
+		return ticketCompletions(words)
+	case "range":
+		return namedPeriods
+	case "queue":
+		return queueActions
+	case "config":
+		return []string{"login", "encode"}
+	case "completion":
+		return completionShells
+	case "help":
+		return commandCompletions()
+	}
+	return nil
+}
+
+// ticketCompletions returns the tickets booked recently followed by the open issues assigned to the user,
+// from the history and the cache of the profile selected on the command line.
+func ticketCompletions(words []string) []string {
+	var completions []string
+	for _, ticket := range recentTickets(completionProfile(words)) {
+		completions = append(completions, strings.TrimSuffix(ticket.Key+"\t"+ticket.Summary, "\t"))
+	}
+	return completions
+}
+
+// completionProfile returns the profile given with -profile on the command line, or else the one the
+// configuration selects, empty when there is no configuration file.
+func completionProfile(words []string) string {
+	var profile string
+	for i, word := range words {
+		var name = strings.TrimLeft(word, "-")
+		switch {
+		case !strings.HasPrefix(word, "-"):
+		case name == "profile" && i+1 < len(words):
+			profile = words[i+1]
+		case strings.HasPrefix(name, "profile="):
+			profile = strings.TrimPrefix(name, "profile=")
+		}
+	}
+	if config := completionConfig(); config != nil {
+		profile, _, _ = config.selectProfile(profile)
+		return profile
+	}
+	return ""
+}
+
+// completionConfig returns the configuration file, nil when there is none or it can't be read.
+func completionConfig() *Config {
+	path, err := configPath()
+	if err != nil {
+		return nil
+	}
+	config, err := readConfig(path)
+	if err != nil || config == nil || len(config.Profiles) == 0 {
+		return nil
+	}
+	return config
+}
@@ -2,9 +2,15 @@ package main
 
 import (
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
+	"time"
+
+	"github.com/praveenprem/timesheet/jira"
 )
 
 /**
@@ -14,22 +20,287 @@ import (
  * Created on: 01/03/2020 18:28
  */
 
-func (app *App) loadConf() {
+type (
+	// Config is the content of the configuration file. Each profile describes one Jira site.
+	Config struct {
+		DefaultProfile string              `json:"default_profile"`
+		Profiles       map[string]*Profile `json:"profiles"`
+	}
+
+	Profile struct {
+		Domain     string      `json:"domain"`
+		BaseURL    string      `json:"base_url,omitempty"`
+		Deployment string      `json:"deployment,omitempty"`
+		Email      string      `json:"email"`
+		Token      TokenSource `json:"token"`
+		Timezone   string      `json:"timezone,omitempty"`
+		Rounding   string      `json:"rounding,omitempty"`
+		// DailyHours, WorkingDays, Hours and WeekStart describe the working week, 8 hours from Monday to
+		// Friday by default. Days are English day names, e.g. "monday" or "mon".
+		DailyHours  float64            `json:"daily_hours,omitempty"`
+		WorkingDays []string           `json:"working_days,omitempty"`
+		Hours       map[string]float64 `json:"hours,omitempty"`
+		WeekStart   string             `json:"week_start,omitempty"`
+		// Calendars are iCalendar (.ics) or YAML files of holidays and leave.
+		Calendars []string `json:"calendars,omitempty"`
+		// BalanceStart is the day, in YYYY-MM-DD format, the flexi-time balance is counted from, starting
+		// at OpeningBalance hours.
+		BalanceStart   string  `json:"balance_start,omitempty"`
+		OpeningBalance float64 `json:"opening_balance,omitempty"`
+		// FiscalYearStart is the month, 1 to 12, the fiscal year starts in for the range report.
+		FiscalYearStart int `json:"fiscal_year_start,omitempty"`
+		// ClosedStatuses are the statuses of the issues time can't be booked on, those of Jira's Done category
+		// when not set. Projects lists the keys of the projects time can be booked on, any when empty.
+		ClosedStatuses []string `json:"closed_statuses,omitempty"`
+		Projects       []string `json:"projects,omitempty"`
+		// DurationFormat is how the tables write durations, "decimal" hours (default) or "jira", e.g. 7h 30m.
+		DurationFormat string `json:"duration_format,omitempty"`
+	}
+
+	// TokenSource tells where the API token of a profile comes from. Source is one of "value" (the token
+	// itself), "env" (the environment variable named by Value), "keyring" (the OS secret store), "pass"
+	// (the pass entry named by Value), "file" (the file at Value) or "command" (the standard output of
+	// the shell command in Value). Command names the pass-compatible program of the pass source, e.g.
+	// gopass, pass by default.
+	TokenSource struct {
+		Source  string `json:"source"`
+		Value   string `json:"value,omitempty"`
+		Command string `json:"command,omitempty"`
+	}
+)
+
+// configPath returns the location of the configuration file. TIMESHEET_CONFIG overrides the default of
+// $XDG_CONFIG_HOME/timesheet/config.json, falling back to ~/.config when XDG_CONFIG_HOME is not set.
+func configPath() (string, error) {
+	if path := os.Getenv("TIMESHEET_CONFIG"); path != "" {
+		return path, nil
+	}
+	var base = os.Getenv("XDG_CONFIG_HOME")
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		base = filepath.Join(home, ".config")
+	}
+	return filepath.Join(base, "timesheet", "config.json"), nil
+}
+
+// readConfig loads the configuration file. A missing file is not an error and yields a nil Config.
+func readConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, &ConfigError{Msg: fmt.Sprintf("unable to read %s", path), Err: err}
+	}
+
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, &ConfigError{Msg: fmt.Sprintf("unable to parse %s", path), Err: err}
+	}
+	return &config, nil
+}
+
+// selectProfile picks the profile named by -profile, then TIMESHEET_PROFILE, then the default profile.
+// A configuration with a single profile needs no name at all.
+func (c *Config) selectProfile(name string) (string, *Profile, error) {
+	if name == "" {
+		name = os.Getenv("TIMESHEET_PROFILE")
+	}
+	if name == "" {
+		name = c.DefaultProfile
+	}
+	if name == "" && len(c.Profiles) == 1 {
+		for only := range c.Profiles {
+			name = only
+		}
+	}
+	if name == "" {
+		return "", nil, &ConfigError{Msg: fmt.Sprintf("no profile selected. use -profile or TIMESHEET_PROFILE with one of: %s",
+			strings.Join(c.profileNames(), ", "))}
+	}
+
+	profile, found := c.Profiles[name]
+	if !found || profile == nil {
+		return "", nil, &ConfigError{Msg: fmt.Sprintf("profile %q not found. available profiles: %s",
+			name, strings.Join(c.profileNames(), ", "))}
+	}
+	return name, profile, nil
+}
+
+func (c *Config) profileNames() []string {
+	var names []string
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// token reads the API token of the profile from its credential store.
+func (p *Profile) token() (string, error) {
+	store, err := newCredentialStore(p.Token)
+	if err != nil {
+		return "", err
+	}
+	return store.Get(credentialAccount(p.Email, p.site()))
+}
+
+// site identifies the Jira site of the profile, its domain or else its base URL.
+func (p *Profile) site() string {
+	if p.Domain != "" {
+		return p.Domain
+	}
+	return p.BaseURL
+}
+
+// writeConfig saves the configuration file, only readable by the user as it may hold tokens.
+func writeConfig(path string, config *Config) error {
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return &ConfigError{Msg: "unable to create the configuration directory", Err: err}
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0600); err != nil {
+		return &ConfigError{Msg: fmt.Sprintf("unable to write %s", path), Err: err}
+	}
+	return nil
+}
+
+func (app *App) loadConf() error {
+	path, err := configPath()
+	if err != nil {
+		return &ConfigError{Msg: "unable to locate the configuration directory", Err: err}
+	}
+	config, err := readConfig(path)
+	if err != nil {
+		return err
+	}
+
+	if config == nil || len(config.Profiles) == 0 {
+		if app.Profile != "" {
+			return &ConfigError{Msg: fmt.Sprintf("profile %q requested but %s has no profiles", app.Profile, path)}
+		}
+		return app.loadEnvConf()
+	}
+
+	name, profile, err := config.selectProfile(app.Profile)
+	if err != nil {
+		return err
+	}
+	return app.applyProfile(name, profile)
+}
+
+func (app *App) applyProfile(name string, profile *Profile) error {
+	if profile.Domain == "" && profile.BaseURL == "" {
+		return &ConfigError{Msg: fmt.Sprintf("profile %q has no domain", name)}
+	}
+	deployment, err := deploymentType(profile.Deployment)
+	if err != nil {
+		return &ConfigError{Msg: fmt.Sprintf("profile %q: %v", name, err)}
+	}
+	if profile.Email == "" && deployment == jira.Cloud {
+		return &ConfigError{Msg: fmt.Sprintf("profile %q has no email", name)}
+	}
+	token, err := profile.token()
+	if err != nil {
+		return err
+	}
+	if token == "" {
+		return &ConfigError{Msg: fmt.Sprintf("profile %q has no API token", name)}
+	}
+
+	app.Configuration.Profile = name
+	app.Configuration.Domain = profile.Domain
+	app.Configuration.BaseURL = profile.BaseURL
+	app.Configuration.Deployment = deployment
+	app.Configuration.Auth = fmt.Sprintf("%s:%s", profile.Email, token)
+	schedule, err := newSchedule(profile.DailyHours, profile.WorkingDays, profile.Hours, profile.WeekStart)
+	if err != nil {
+		return &ConfigError{Msg: fmt.Sprintf("profile %q has an invalid schedule: %v", name, err)}
+	}
+	app.Configuration.Schedule = &schedule
+	calendar, err := loadCalendars(profile.Calendars)
+	if err != nil {
+		return &ConfigError{Msg: fmt.Sprintf("profile %q has an invalid calendar", name), Err: err}
+	}
+	app.Configuration.Calendar = calendar
+	if profile.BalanceStart != "" {
+		start, err := time.Parse(YmdFormat, profile.BalanceStart)
+		if err != nil {
+			return &ConfigError{Msg: fmt.Sprintf("profile %q has an invalid balance_start, expected YYYY-MM-DD", name)}
+		}
+		app.Configuration.BalanceStart = start
+	}
+	app.Configuration.OpeningBalance = int(profile.OpeningBalance * 3600)
+	if profile.FiscalYearStart < 0 || profile.FiscalYearStart > 12 {
+		return &ConfigError{Msg: fmt.Sprintf("profile %q has an invalid fiscal_year_start, expected a month from 1 to 12", name)}
+	}
+	app.Configuration.FiscalYearStart = time.Month(profile.FiscalYearStart)
+	app.Configuration.ClosedStatuses = profile.ClosedStatuses
+	app.Configuration.Projects = profile.Projects
+	app.Configuration.Rounding = profile.Rounding
+	if profile.DurationFormat != "" && !isCommand(durationFormats, profile.DurationFormat) {
+		return &ConfigError{Msg: fmt.Sprintf("profile %q has an invalid duration_format, expected one of: %s",
+			name, strings.Join(durationFormats, ", "))}
+	}
+	app.Configuration.DurationFormat = profile.DurationFormat
+	if profile.Timezone != "" {
+		location, err := time.LoadLocation(profile.Timezone)
+		if err != nil {
+			return &ConfigError{Msg: fmt.Sprintf("profile %q has an invalid timezone", name), Err: err}
+		}
+		app.Configuration.Location = location
+	}
+	// The start and length of the worklog are read again now the timezone and week of the profile are known.
+	if app.TimeSpent != "" {
+		if err := app.parseSpent(); err != nil {
+			return err
+		}
+	}
+	return app.resolveDate()
+}
+
+// deploymentType validates the deployment of a profile, Jira Cloud unless told otherwise. Data Center
+// shares the API of Server.
+func deploymentType(deployment string) (string, error) {
+	switch strings.ToLower(deployment) {
+	case "", jira.Cloud:
+		return jira.Cloud, nil
+	case jira.Server, "datacenter", "data-center":
+		return jira.Server, nil
+	default:
+		return "", fmt.Errorf("unknown deployment %q. use cloud, server or datacenter", deployment)
+	}
+}
+
+// loadEnvConf reads the legacy Base64 encoded "email:token;domain" string from TIMESHEET.
+func (app *App) loadEnvConf() error {
 	if rawConf := os.Getenv("TIMESHEET"); rawConf == "" {
-		panic("please export \"TIMESHEET\" with Base64 encoded Atlassian data in following format: email:token;domain")
+		return &ConfigError{Msg: "please create a configuration file or export \"TIMESHEET\" with Base64 encoded Atlassian data in following format: email:token;domain"}
 	} else {
-		if conf, err := base64.StdEncoding.DecodeString(rawConf); err != nil {
-			panic("config is not Base64 encoded.")
+		if conf, err := base64.StdEncoding.DecodeString(strings.TrimSpace(rawConf)); err != nil {
+			return &ConfigError{Msg: "config is not Base64 encoded", Err: err}
 		} else {
-			var config = strings.Split(string(conf), ";")
+			var config = strings.Split(strings.TrimSpace(string(conf)), ";")
+			if len(config) < 2 || config[1] == "" {
+				return &ConfigError{Msg: "config is missing the Atlassian domain. expected format: email:token;domain"}
+			}
+			if !strings.Contains(config[0], ":") {
+				return &ConfigError{Msg: "config is missing the API token. expected format: email:token;domain"}
+			}
 			app.Configuration.Auth = config[0]
 			app.Configuration.Domain = config[1]
 		}
 	}
-
+	return nil
 }
 
 func (app *App) CredentialEncode() {
 	var token = base64.StdEncoding.EncodeToString([]byte(app.Encode))
-	fmt.Println(token)
+	fmt.Fprintln(app.out(), token)
 }
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSelectProfile(t *testing.T) {
+	var work, home = &Profile{Domain: "work"}, &Profile{Domain: "home"}
+	var config = &Config{Profiles: map[string]*Profile{"work": work, "home": home}}
+	for _, c := range []struct {
+		name, env, fallback string
+		want                string
+	}{
+		{"home", "work", "work", "home"},
+		{"", "home", "work", "home"},
+		{"", "", "work", "work"},
+		{"", "", "", ""},
+		{"office", "", "", ""},
+		{"", "office", "work", ""},
+	} {
+		t.Setenv("TIMESHEET_PROFILE", c.env)
+		config.DefaultProfile = c.fallback
+		name, profile, err := config.selectProfile(c.name)
+		var configErr *ConfigError
+		switch {
+		case c.want == "" && !errors.As(err, &configErr):
+			t.Errorf("selectProfile(%q) with TIMESHEET_PROFILE=%q and default %q = %v, want a configuration error",
+				c.name, c.env, c.fallback, err)
+		case c.want != "" && (err != nil || name != c.want || profile != config.Profiles[c.want]):
+			t.Errorf("selectProfile(%q) with TIMESHEET_PROFILE=%q and default %q = %q, %v, want %q",
+				c.name, c.env, c.fallback, name, err, c.want)
+		}
+	}
+
+	t.Setenv("TIMESHEET_PROFILE", "")
+	var single = &Config{Profiles: map[string]*Profile{"work": work}}
+	if name, profile, err := single.selectProfile(""); err != nil || name != "work" || profile != work {
+		t.Errorf("selectProfile of the only profile = %q, %v, want work", name, err)
+	}
+}
+
+func TestLoadConf(t *testing.T) {
+	var dir = t.TempDir()
+	t.Setenv("TIMESHEET_CONFIG", filepath.Join(dir, "config.json"))
+	t.Setenv("TIMESHEET_PROFILE", "")
+	t.Setenv("TIMESHEET", base64.StdEncoding.EncodeToString([]byte("dev@example.com:secret;example.atlassian.net")))
+
+	var app App
+	if err := app.loadConf(); err != nil {
+		t.Fatal(err)
+	}
+	if app.Configuration.Auth != "dev@example.com:secret" || app.Configuration.Domain != "example.atlassian.net" {
+		t.Errorf("without a configuration file got auth %q and domain %q, want those of TIMESHEET",
+			app.Configuration.Auth, app.Configuration.Domain)
+	}
+
+	var configErr *ConfigError
+	app = App{Profile: "work"}
+	if err := app.loadConf(); !errors.As(err, &configErr) {
+		t.Errorf("-profile without profiles = %v, want a configuration error", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(`{"default_profile": "home", "profiles": {
+		"work": {"domain": "work.atlassian.net", "email": "dev@example.com", "token": {"source": "value", "value": "w"}},
+		"home": {"domain": "home.atlassian.net", "email": "dev@example.com", "token": {"source": "value", "value": "h"}}}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	app = App{Profile: "work"}
+	if err := app.loadConf(); err != nil {
+		t.Fatal(err)
+	}
+	if app.Configuration.Profile != "work" || app.Configuration.Auth != "dev@example.com:w" {
+		t.Errorf("-profile work loaded profile %q with auth %q", app.Configuration.Profile, app.Configuration.Auth)
+	}
+	app = App{}
+	if err := app.loadConf(); err != nil || app.Configuration.Domain != "home.atlassian.net" {
+		t.Errorf("the default profile loaded domain %q, %v, want home.atlassian.net", app.Configuration.Domain, err)
+	}
+}
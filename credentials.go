@@ -0,0 +1,348 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/praveenprem/timesheet/jira"
+)
+
+/**
+ * Package name: main
+ * Project name: timesheet
+ * Created on: 18/10/2026 10:30
+ */
+
+// keyringService is the service name the API tokens are filed under in the OS secret store.
+const keyringService = "timesheet"
+
+// CredentialStore reads and writes the API token of an account, where the account identifies the user on
+// a Jira site.
+type CredentialStore interface {
+	Get(account string) (string, error)
+	Set(account string, token string) error
+}
+
+type (
+	// valueStore holds the token in the configuration file itself.
+	valueStore struct {
+		token string
+	}
+
+	// envStore reads the token from an environment variable.
+	envStore struct {
+		name string
+	}
+
+	// keyringStore keeps the token in the Secret Service (Linux) or the login keychain (macOS).
+	keyringStore struct{}
+
+	// passStore keeps the token in a pass-compatible password store under entry.
+	passStore struct {
+		command string
+		entry   string
+	}
+
+	// fileStore keeps the token in a file only readable by the user.
+	fileStore struct {
+		path string
+	}
+
+	// commandStore reads the token from the standard output of a shell command.
+	commandStore struct {
+		command string
+	}
+)
+
+// credentialBackends lists the token sources which can be written by the login flow.
+var credentialBackends = []string{"keyring", "pass", "file"}
+
+// newCredentialStore returns the store described by the token source of a profile.
+func newCredentialStore(source TokenSource) (CredentialStore, error) {
+	switch source.Source {
+	case "", "value":
+		return &valueStore{token: source.Value}, nil
+	case "env":
+		return &envStore{name: source.Value}, nil
+	case "keyring":
+		return &keyringStore{}, nil
+	case "pass":
+		var command = source.Command
+		if command == "" {
+			command = "pass"
+		}
+		return &passStore{command: command, entry: source.Value}, nil
+	case "file":
+		return &fileStore{path: source.Value}, nil
+	case "command":
+		return &commandStore{command: source.Value}, nil
+	default:
+		return nil, &ConfigError{Msg: fmt.Sprintf("unknown token source %q", source.Source)}
+	}
+}
+
+func (s *valueStore) Get(account string) (string, error) {
+	return s.token, nil
+}
+
+func (s *valueStore) Set(account string, token string) error {
+	return &ConfigError{Msg: "tokens stored in the configuration file can't be written by login, edit the file instead"}
+}
+
+func (s *envStore) Get(account string) (string, error) {
+	if token := os.Getenv(s.name); token != "" {
+		return token, nil
+	}
+	return "", &ConfigError{Msg: fmt.Sprintf("environment variable %q holding the API token is empty", s.name)}
+}
+
+func (s *envStore) Set(account string, token string) error {
+	return &ConfigError{Msg: fmt.Sprintf("export the API token as %s instead", s.name)}
+}
+
+func (s *keyringStore) Get(account string) (string, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		return runCredentialCommand("", "security", "find-generic-password", "-s", keyringService, "-a", account, "-w")
+	case "linux", "freebsd", "openbsd", "netbsd":
+		return runCredentialCommand("", "secret-tool", "lookup", "service", keyringService, "account", account)
+	default:
+		return "", &ConfigError{Msg: fmt.Sprintf("the keyring token source is not supported on %s", runtime.GOOS)}
+	}
+}
+
+func (s *keyringStore) Set(account string, token string) error {
+	var err error
+	switch runtime.GOOS {
+	case "darwin":
+		// security reads the command from stdin in interactive mode, keeping the token out of the process list.
+		_, err = runCredentialCommand(fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n",
+			securityQuote(keyringService), securityQuote(account), securityQuote(token)), "security", "-i")
+	case "linux", "freebsd", "openbsd", "netbsd":
+		_, err = runCredentialCommand(token, "secret-tool", "store", "--label", fmt.Sprintf("%s %s", keyringService, account),
+			"service", keyringService, "account", account)
+	default:
+		err = &ConfigError{Msg: fmt.Sprintf("the keyring token source is not supported on %s", runtime.GOOS)}
+	}
+	return err
+}
+
+// securityQuote quotes a word of a command given to security -i.
+func securityQuote(word string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(word) + `"`
+}
+
+func (s *passStore) name(account string) string {
+	if s.entry != "" {
+		return s.entry
+	}
+	return passEntry(account)
+}
+
+func (s *passStore) Get(account string) (string, error) {
+	out, err := runCredentialCommand("", s.command, "show", s.name(account))
+	if err != nil {
+		return "", err
+	}
+	// pass keeps the password on the first line, anything after is metadata
+	return strings.SplitN(out, "\n", 2)[0], nil
+}
+
+func (s *passStore) Set(account string, token string) error {
+	_, err := runCredentialCommand(token+"\n", s.command, "insert", "--multiline", "--force", s.name(account))
+	return err
+}
+
+func (s *fileStore) Get(account string) (string, error) {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return "", &ConfigError{Msg: "unable to read the token file", Err: err}
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0077 != 0 {
+		return "", &ConfigError{Msg: fmt.Sprintf("token file %s is accessible by other users, run: chmod 600 %s", s.path, s.path)}
+	}
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return "", &ConfigError{Msg: "unable to read the token file", Err: err}
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func (s *fileStore) Set(account string, token string) error {
+	// The token goes to a new file renamed over the old one, as writing in place keeps the mode of a file
+	// others may read.
+	if err := writeFileAtomic(s.path, []byte(token+"\n"), 0600); err != nil {
+		return &ConfigError{Msg: "unable to write the token file", Err: err}
+	}
+	return nil
+}
+
+func (s *commandStore) Get(account string) (string, error) {
+	return runCredentialCommand("", "sh", "-c", s.command)
+}
+
+func (s *commandStore) Set(account string, token string) error {
+	return &ConfigError{Msg: "the command token source is read only, store the token where the command reads it from"}
+}
+
+// runCredentialCommand runs the helper with input on stdin and returns its trimmed standard output.
+func runCredentialCommand(input string, name string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	var cmd = exec.Command(name, args...)
+	cmd.Stdin = strings.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		var msg = strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", &ConfigError{Msg: fmt.Sprintf("%s failed: %s", name, msg)}
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// credentialAccount identifies the user on a Jira site in the secret stores.
+func credentialAccount(email string, domain string) string {
+	return fmt.Sprintf("%s/%s", domain, email)
+}
+
+func passEntry(account string) string {
+	return fmt.Sprintf("%s/%s", keyringService, account)
+}
+
+// CredentialLogin asks for the Jira site, email and API token, stores the token in the backend chosen by
+// -backend and saves the profile to the configuration file. It only sets up Jira Cloud profiles.
+func (app *App) CredentialLogin(in io.Reader, out io.Writer) error {
+	var reader = bufio.NewReader(in)
+	var prompt = func(label string) (string, error) {
+		fmt.Fprintf(out, "%s: ", label)
+		line, err := reader.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return "", &UsageError{Msg: fmt.Sprintf("no %s given", strings.ToLower(label))}
+		}
+		return strings.TrimSpace(line), nil
+	}
+	var promptSecret = func(label string) (string, error) {
+		if restore := hideInput(in); restore != nil {
+			defer func() {
+				restore()
+				fmt.Fprintln(out)
+			}()
+		}
+		return prompt(label)
+	}
+
+	path, err := configPath()
+	if err != nil {
+		return &ConfigError{Msg: "unable to locate the configuration directory", Err: err}
+	}
+	config, err := readConfig(path)
+	if err != nil {
+		return err
+	}
+	if config == nil {
+		config = &Config{}
+	}
+	if config.Profiles == nil {
+		config.Profiles = make(map[string]*Profile)
+	}
+
+	var name = app.Profile
+	if name == "" {
+		name = "default"
+	}
+	// Server and Data Center take a personal access token without an email, which the prompts don't ask for.
+	if profile := config.Profiles[name]; profile != nil {
+		if deployment, err := deploymentType(profile.Deployment); err == nil && deployment == jira.Server {
+			return &ConfigError{Msg: fmt.Sprintf("profile %q is a Jira Server or Data Center profile and login only sets up Jira Cloud. "+
+				"give its personal access token in %s instead", name, path)}
+		}
+	}
+
+	domain, err := prompt("Jira domain")
+	if err != nil {
+		return err
+	}
+	email, err := prompt("Email")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, "Create a token at https://id.atlassian.com/manage/api-tokens")
+	token, err := promptSecret("API token")
+	if err != nil {
+		return err
+	}
+	if domain == "" || email == "" || token == "" {
+		return usageErrorf("the Jira domain, email and API token are all required")
+	}
+
+	var account = credentialAccount(email, domain)
+	var source = TokenSource{Source: app.Backend}
+	switch app.Backend {
+	case "keyring":
+	case "pass":
+		source.Value = passEntry(account)
+		if current := config.Profiles[name]; current != nil && current.Token.Source == "pass" {
+			source.Command = current.Token.Command
+		}
+	case "file":
+		source.Value = filepath.Join(filepath.Dir(path), fmt.Sprintf("%s.token", name))
+	default:
+		return usageErrorf("unknown credential backend %q. use one of: %s", app.Backend, strings.Join(credentialBackends, ", "))
+	}
+
+	store, err := newCredentialStore(source)
+	if err != nil {
+		return err
+	}
+	if err := store.Set(account, token); err != nil {
+		return err
+	}
+
+	var profile = config.Profiles[name]
+	if profile == nil {
+		profile = &Profile{}
+		config.Profiles[name] = profile
+	}
+	profile.Domain = domain
+	profile.Email = email
+	profile.Token = source
+	if config.DefaultProfile == "" {
+		config.DefaultProfile = name
+	}
+	if err := writeConfig(path, config); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Profile %q saved to %s with the token in the %s backend\n", name, path, app.Backend)
+	return nil
+}
+
+// hideInput turns off the echo of the terminal in reads from, returning the function turning it back on. It
+// returns nil when in isn't a terminal, or its echo can't be changed.
+func hideInput(in io.Reader) func() {
+	file, ok := in.(*os.File)
+	if !ok || runtime.GOOS == "windows" {
+		return nil
+	}
+	if info, err := file.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return nil
+	}
+	var stty = func(arg string) error {
+		var cmd = exec.Command("stty", arg)
+		cmd.Stdin = file
+		return cmd.Run()
+	}
+	if err := stty("-echo"); err != nil {
+		return nil
+	}
+	return func() { _ = stty("echo") }
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestCredentialStores(t *testing.T) {
+	var dir = t.TempDir()
+	var tokenFile = filepath.Join(dir, "work.token")
+	if err := os.WriteFile(tokenFile, []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("JIRA_TOKEN", "from-env")
+
+	for _, c := range []struct {
+		source TokenSource
+		want   string
+	}{
+		{TokenSource{Value: "from-config"}, "from-config"},
+		{TokenSource{Source: "value", Value: "from-config"}, "from-config"},
+		{TokenSource{Source: "env", Value: "JIRA_TOKEN"}, "from-env"},
+		{TokenSource{Source: "file", Value: tokenFile}, "from-file"},
+		{TokenSource{Source: "command", Value: "echo from-command"}, "from-command"},
+	} {
+		store, err := newCredentialStore(c.source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token, err := store.Get("example.atlassian.net/dev@example.com"); err != nil || token != c.want {
+			t.Errorf("%+v gave %q, %v, want %q", c.source, token, err, c.want)
+		}
+	}
+
+	var configErr *ConfigError
+	for _, source := range []TokenSource{
+		{Source: "vault"},
+		{Source: "env", Value: "TIMESHEET_UNSET_TOKEN"},
+		{Source: "file", Value: filepath.Join(dir, "missing.token")},
+		{Source: "command", Value: "echo denied >&2; exit 1"},
+	} {
+		store, err := newCredentialStore(source)
+		if err == nil {
+			_, err = store.Get("example.atlassian.net/dev@example.com")
+		}
+		if !errors.As(err, &configErr) {
+			t.Errorf("%+v gave %v, want a configuration error", source, err)
+		}
+	}
+	for _, source := range []TokenSource{{Source: "value"}, {Source: "env", Value: "JIRA_TOKEN"}, {Source: "command", Value: "true"}} {
+		store, _ := newCredentialStore(source)
+		if err := store.Set("example.atlassian.net/dev@example.com", "secret"); !errors.As(err, &configErr) {
+			t.Errorf("writing to %+v gave %v, want a configuration error", source, err)
+		}
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file permissions aren't checked on windows")
+	}
+	var path = filepath.Join(t.TempDir(), "tokens", "work.token")
+	var store = &fileStore{path: path}
+	if err := store.Set("", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	if token, err := store.Get(""); err != nil || token != "secret" {
+		t.Errorf("Get = %q, %v, want the token set", token, err)
+	}
+
+	if err := os.Chmod(path, 0644); err != nil {
+		t.Fatal(err)
+	}
+	var configErr *ConfigError
+	if _, err := store.Get(""); !errors.As(err, &configErr) || !strings.Contains(err.Error(), "chmod 600") {
+		t.Errorf("Get of a readable token file = %v, want it refused", err)
+	}
+	if err := store.Set("", "rotated"); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("token file mode after Set = %v, %v, want 0600", info.Mode().Perm(), err)
+	}
+	if token, err := store.Get(""); err != nil || token != "rotated" {
+		t.Errorf("Get after Set restricted the file = %q, %v, want the new token", token, err)
+	}
+}
+
+func TestPassStore(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake pass is a shell script")
+	}
+	// The fake pass keeps each entry in a file under its directory, the password first and metadata after.
+	var dir = t.TempDir()
+	var pass = filepath.Join(dir, "pass")
+	if err := os.WriteFile(pass, []byte(`#!/bin/sh
+entry="$(dirname "$0")/$(echo "$@" | awk '{ print $NF }' | tr / _)"
+case "$1" in
+show) [ -f "$entry" ] && cat "$entry" || { echo "Error: not in the password store." >&2; exit 1; } ;;
+insert) cat > "$entry"; echo "url: example.atlassian.net" >> "$entry" ;;
+esac
+`), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	var account = credentialAccount("dev@example.com", "example.atlassian.net")
+	store, err := newCredentialStore(TokenSource{Source: "pass", Command: pass})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var configErr *ConfigError
+	if _, err := store.Get(account); !errors.As(err, &configErr) || !strings.Contains(err.Error(), "not in the password store") {
+		t.Errorf("Get of a missing entry = %v, want the error of pass", err)
+	}
+	if err := store.Set(account, "secret"); err != nil {
+		t.Fatal(err)
+	}
+	if token, err := store.Get(account); err != nil || token != "secret" {
+		t.Errorf("Get = %q, %v, want the first line of the entry", token, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "timesheet_example.atlassian.net_dev@example.com")); err != nil {
+		t.Errorf("the entry wasn't filed under the account: %v", err)
+	}
+}
+
+func TestProfileToken(t *testing.T) {
+	t.Setenv("JIRA_TOKEN", "from-env")
+	var profile = &Profile{Domain: "example.atlassian.net", Email: "dev@example.com", Token: TokenSource{Source: "env", Value: "JIRA_TOKEN"}}
+	if token, err := profile.token(); err != nil || token != "from-env" {
+		t.Errorf("token = %q, %v, want from-env", token, err)
+	}
+	profile.Token = TokenSource{Source: "keychain"}
+	if _, err := profile.token(); err == nil {
+		t.Error("token of an unknown source gave no error")
+	}
+}
+
+func TestCredentialLogin(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file permissions aren't checked on windows")
+	}
+	var dir = t.TempDir()
+	var path = filepath.Join(dir, "config.json")
+	t.Setenv("TIMESHEET_CONFIG", path)
+	t.Setenv("TIMESHEET_PROFILE", "")
+
+	var out bytes.Buffer
+	var app = &App{Profile: "work", Backend: "file"}
+	if err := app.CredentialLogin(strings.NewReader("example.atlassian.net\ndev@example.com\nsecret\n"), &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `Profile "work" saved`) {
+		t.Errorf("login wrote %q", out.String())
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "work.token")); err != nil || string(data) != "secret\n" {
+		t.Errorf("token file = %q, %v", data, err)
+	}
+
+	app = &App{}
+	if err := app.loadConf(); err != nil {
+		t.Fatal(err)
+	}
+	if app.Configuration.Profile != "work" || app.Configuration.Auth != "dev@example.com:secret" {
+		t.Errorf("the saved profile loaded as %q with auth %q", app.Configuration.Profile, app.Configuration.Auth)
+	}
+
+	var usageErr *UsageError
+	app = &App{Backend: "vault"}
+	if err := app.CredentialLogin(strings.NewReader("example.atlassian.net\ndev@example.com\nsecret\n"), &out); !errors.As(err, &usageErr) {
+		t.Errorf("login to an unknown backend = %v, want a usage error", err)
+	}
+	if err := app.CredentialLogin(strings.NewReader("example.atlassian.net\n"), &out); !errors.As(err, &usageErr) {
+		t.Errorf("login without an email = %v, want a usage error", err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"profiles": {"self-hosted": {"base_url": "https://jira.example.com",
+		"deployment": "server", "token": {"source": "keyring"}}}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var configErr *ConfigError
+	app = &App{Profile: "self-hosted", Backend: "file"}
+	if err := app.CredentialLogin(strings.NewReader("jira.example.com\n\nsecret\n"), &out); !errors.As(err, &configErr) {
+		t.Errorf("login to a server profile = %v, want a configuration error", err)
+	}
+}
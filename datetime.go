@@ -4,7 +4,6 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
-	"strconv"
 	"strings"
 	"time"
 )
@@ -18,87 +17,169 @@ import (
 
 var (
 	DateFormat, _      = regexp.Compile(`[0-9]{4}-[0-9]{2}-[0-9]{2}`)
-	RelativeDateFormat = regexp.MustCompile(`^(?P<Operator>[\-|\+])(?P<Days>[0-9]+)`)
+	RelativeDateFormat = regexp.MustCompile(`^(?P<Operator>[-+])(?P<Count>[0-9]+)(?P<Unit>d|w|wd)?$`)
+	ClockFormat        = regexp.MustCompile(`^([01]?[0-9]|2[0-3]):([0-5][0-9])$`)
 	YmdFormat          = "2006-01-02"
+	YmdHmFormat        = "2006-01-02 15:04"
 	HmsFormat          = "15:04:05"
 )
 
+// defaultStartHour is the time of day worklogs start at when -d gives a day without a time and -at isn't given.
+const defaultStartHour = 9
+
+// dateExamples is shown when a date expression can't be read.
+const dateExamples = "use e.g. yesterday, last friday, mon, -1, -2wd, -1w, 2006-01-02 or 2006-01-02 13:30"
+
+// location returns the timezone dates are shown and booked in: the profile's timezone, or the local one.
+func (app *App) location() *time.Location {
+	if app.Configuration.Location == nil {
+		return time.Local
+	}
+	return app.Configuration.Location
+}
+
 func (app *App) getDateTime() string {
-	var now = time.Now()
+	var now = time.Now().In(app.location())
 	return fmt.Sprintf("%sT%s", now.Format(YmdFormat), app.getTime())
 }
 
 func (app *App) getTime() string {
-	var now = time.Now()
-	return fmt.Sprintf("%s.000+0000", now.Format(HmsFormat))
+	return time.Now().In(app.location()).Format("15:04:05.000-0700")
 }
 
 func (app *App) getDate() string {
 	return strings.Split(app.Started, "T")[0]
 }
 
-func (app *App) getTimeFixed() string {
-	return "09:00:00.000+0000"
-}
+// parseDate reads the date expression of -d relative to now:
+//
+//   - today, yesterday or tomorrow
+//   - a day of the current week, e.g. mon or friday, or of the week before, e.g. last friday
+//   - a number of days (-1, +2 or -3d), weeks (-1w) or working days (-2wd) from today, working days skipping
+//     the days off of the schedule and the calendars
+//   - a full date, 2006-01-02, optionally with the time the worklog started, 2006-01-02 13:30
+//
+// It returns the start of the day, or the time given with the date when hasTime is true, in the profile's
+// timezone.
+func (app *App) parseDate(expr string, now time.Time) (date time.Time, hasTime bool, err error) {
+	var year, month, day = now.In(app.location()).Date()
+	var today = time.Date(year, month, day, 0, 0, 0, 0, app.location())
+	var text = strings.Join(strings.Fields(strings.ToLower(expr)), " ")
+
+	switch text {
+	case "", "today":
+		return today, false, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), false, nil
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), false, nil
+	}
 
-func (app *App) isDateMatch(datetime string) bool {
-	dateExpr, _ := regexp.Compile("([0-9]{4}-[0-9]{2}-[0-9]{2})")
-	date, err := time.Parse(YmdFormat, dateExpr.FindString(datetime))
-	if err != nil {
-		panic(err)
+	if match := RelativeDateFormat.FindStringSubmatch(text); match != nil {
+		var count = atoi(match[2])
+		if match[1] == "-" {
+			count = -count
+		}
+		switch match[3] {
+		case "w":
+			return today.AddDate(0, 0, 7*count), false, nil
+		case "wd":
+			date, err := app.addWorkingDays(today, count)
+			return date, false, err
+		default:
+			return today.AddDate(0, 0, count), false, nil
+		}
+	}
+
+	var weeks int
+	if weekday := strings.TrimPrefix(text, "last "); weekday != text {
+		text, weeks = weekday, -1
+	}
+	if weekday, err := parseWeekday(text); err == nil {
+		var weekStart, _ = app.schedule().weekOf(today)
+		var offset = (int(weekday) - int(weekStart.Weekday()) + 7) % 7
+		return weekStart.AddDate(0, 0, offset+7*weeks), false, nil
+	}
+	if weeks != 0 {
+		return time.Time{}, false, fmt.Errorf("invalid day of the week in %q. %s", expr, dateExamples)
 	}
 
-	if date.Format(YmdFormat) == app.getDate() {
-		return true
+	if date, err := time.ParseInLocation(YmdFormat, text, app.location()); err == nil {
+		return date, false, nil
 	}
-	return false
+	if date, err := time.ParseInLocation(YmdHmFormat, text, app.location()); err == nil {
+		return date, true, nil
+	}
+	return time.Time{}, false, fmt.Errorf("invalid date %q. %s", expr, dateExamples)
 }
 
-func (app *App) isDateBetween(datetime string, start time.Time, end time.Time) bool {
-	dateExpr := regexp.MustCompile(`([0-9]{4})-([0-9]{2})-([0-9]{2})T([0-9]{2}):([0-9]{2}):([0-9]{2})`)
-	timeArray := dateExpr.FindAllStringSubmatch(datetime, -1)
-	year, month, day, hour, minute, second := timeArray[0][1], timeArray[0][2], timeArray[0][3], timeArray[0][4], timeArray[0][5], timeArray[0][6]
+// addWorkingDays moves the date by count working days, the days the schedule expects time on that the
+// calendars don't take off in full.
+func (app *App) addWorkingDays(date time.Time, count int) (time.Time, error) {
+	var step = 1
+	if count < 0 {
+		step, count = -1, -count
+	}
+	// A year without a single working day means the schedule has none.
+	for skipped := 0; count > 0; skipped++ {
+		if skipped > 366 {
+			return time.Time{}, errors.New("the schedule has no working days to count")
+		}
+		date = date.AddDate(0, 0, step)
+		if expected, _ := app.expectedOn(date); expected > 0 {
+			count--
+			skipped = 0
+		}
+	}
+	return date, nil
+}
 
-	date := time.Date(toInt(year), time.Month(toInt(month)), toInt(day), toInt(hour), toInt(minute), toInt(second), 0, start.Location())
+// parseClock reads the time of day given with -at, e.g. 13:30, returning the hour and minute.
+func parseClock(clock string) (int, int, error) {
+	var match = ClockFormat.FindStringSubmatch(strings.TrimSpace(clock))
+	if match == nil {
+		return 0, 0, fmt.Errorf("invalid time of day %q. use hh:mm, e.g. 13:30", clock)
+	}
+	return atoi(match[1]), atoi(match[2]), nil
+}
 
-	var normalizedStartDate, _ = fullDay(start)
-	var _, normalizedEndDate = fullDay(end)
+// localTime reads a Jira started timestamp, offset included, and returns it in the profile's timezone.
+func (app *App) localTime(started string) (time.Time, error) {
+	date, err := time.Parse(jiraTimestampFormat, started)
+	if err != nil {
+		return date, err
+	}
+	return date.In(app.location()), nil
+}
 
-	if date.After(normalizedStartDate) && date.Before(normalizedEndDate) {
-		return true
+// localDate returns the day a Jira started timestamp falls on in the profile's timezone, or the date written
+// in it when it isn't a full timestamp.
+func (app *App) localDate(started string) string {
+	if date, err := app.localTime(started); err == nil {
+		return date.Format(YmdFormat)
 	}
-	return false
+	return DateFormat.FindString(started)
 }
 
-func (app *App) getWeek() (time.Time, time.Time) {
-	var now, err = time.Parse(YmdFormat, app.getDate())
-	if err != nil {
-		panic(err)
-	}
-	var weekBegin time.Time
-	var weekEnd time.Time
-	var dayPos = int(now.Weekday())
-	var spanLeft = 1 - dayPos
-	var spanRight = 5 - dayPos
-	weekBegin = now.AddDate(0, 0, spanLeft)
-	weekEnd = now.AddDate(0, 0, spanRight)
-	return weekBegin, weekEnd
+func (app *App) isDateMatch(datetime string) bool {
+	return app.localDate(datetime) == app.getDate()
 }
 
-func (app *App) GetDateFromRelative() (*time.Time, error) {
-	var now = time.Now()
-	var date time.Time
-	match := RelativeDateFormat.FindStringSubmatch(app.Started)
-	numDays := toInt(match[2])
+// isDateBetween tells whether a Jira started timestamp falls between the start and end days, both included,
+// in the profile's timezone.
+func (app *App) isDateBetween(datetime string, start time.Time, end time.Time) bool {
+	var day = app.localDate(datetime)
+	return day != "" && day >= start.Format(YmdFormat) && day <= end.Format(YmdFormat)
+}
 
-	if match[1] == "-" {
-		date = now.AddDate(0, 0, -numDays)
-	} else if match[1] == "+" {
-		date = now.AddDate(0, 0, +numDays)
-	} else {
-		return nil, errors.New("invalid operation given")
+// getWeek returns the first and last day of the week of the date, starting on the schedule's week start.
+func (app *App) getWeek() (time.Time, time.Time, error) {
+	var now, err = time.Parse(YmdFormat, app.getDate())
+	if err != nil {
+		return time.Time{}, time.Time{}, err
 	}
-	return &date, nil
+	weekBegin, weekEnd := app.schedule().weekOf(now)
+	return weekBegin, weekEnd, nil
 }
 
 func fullDay(date time.Time) (time.Time, time.Time) {
@@ -106,41 +187,26 @@ func fullDay(date time.Time) (time.Time, time.Time) {
 	return time.Date(yeah, month, day, 0, 0, 0, 0, date.Location()), time.Date(yeah, month, day, 23, 59, 59, 0, date.Location())
 }
 
-func getInHours(seconds int) float64 {
-	return float64(seconds) / float64(3600)
-}
-
 func getDateOfWeek(datetime string) string {
 	dateExpr, _ := regexp.Compile("([0-9]{4}-[0-9]{2}-[0-9]{2})")
 	date, err := time.Parse(YmdFormat, dateExpr.FindString(datetime))
 	if err != nil {
-		panic(err)
+		return ""
 	}
 	return date.Weekday().String()
 }
 
-func toInt(s string) int {
-	value, err := strconv.ParseInt(s, 10, 64)
-	if err != nil {
-		panic(err)
-	}
-	return int(value)
-}
-func (app *App) getMonth() (time.Time, time.Time, map[int][]time.Time) {
-	var weekNumbers = make(map[int][]time.Time)
+func (app *App) getMonth() (time.Time, time.Time, map[int][]time.Time, error) {
+	var weekNumbers map[int][]time.Time
 	var start, end time.Time
 	var now, err = time.Parse(YmdFormat, app.getDate())
 	if err != nil {
-		panic(err)
+		return start, end, nil, err
 	}
 
 	start = time.Date(now.Year(), now.Month(), 1, now.Hour(), 0, 0, 0, now.Location())
 	end = time.Date(now.Year(), now.Month()+1, 0, 0, 0, 0, 0, now.Location())
-	for i := start.Day(); i <= end.Day(); i++ {
-		tmpDate := time.Date(now.Year(), now.Month(), i, 0, 0, 0, 0, now.Location())
-		_, wNumber := tmpDate.ISOWeek()
-		weekNumbers[wNumber] = append(weekNumbers[wNumber], tmpDate)
-	}
+	weekNumbers = app.schedule().weeksOfMonth(now)
 
-	return start, end, weekNumbers
+	return start, end, weekNumbers, nil
 }
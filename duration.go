@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+/**
+ * Package name: main
+ * Project name: timesheet
+ * Created on: 18/10/2026 11:13
+ */
+
+// Styles of the duration_format setting, how the tables write durations.
+const (
+	// DurationDecimal writes durations as decimal hours, e.g. 7.50.
+	DurationDecimal = "decimal"
+	// DurationJira writes durations in hours and minutes the way Jira does, e.g. 7h 30m.
+	DurationJira = "jira"
+)
+
+// durationFormats lists the values accepted by duration_format.
+var durationFormats = []string{DurationDecimal, DurationJira}
+
+var (
+	clockDuration = regexp.MustCompile(`^([0-9]+):([0-5][0-9])$`)
+	durationPart  = regexp.MustCompile(`^([0-9]+(?:\.[0-9]+)?|\.[0-9]+) *([wdhm]?)`)
+)
+
+// durationUnits are the units of a duration in the order they are given, from weeks down to minutes.
+const durationUnits = "wdhm"
+
+// Duration is a time spent, in seconds.
+type Duration int
+
+// ParseDuration reads a time spent given the way Jira accepts it, e.g. 1h 30m, 1.5h, 90m, 90 or 2d, or as a
+// clock, e.g. 1:30. Days and weeks are the working day and week of the schedule. Each part needs its unit,
+// given once from weeks down to minutes, but for a lone number of minutes. Durations of less than a minute
+// are refused, as Jira does.
+func ParseDuration(input string, schedule Schedule) (Duration, error) {
+	var text = strings.ToLower(strings.TrimSpace(input))
+	var seconds float64
+	if match := clockDuration.FindStringSubmatch(text); match != nil {
+		seconds = float64(atoi(match[1])*3600 + atoi(match[2])*60)
+	} else {
+		if text == "" {
+			return 0, fmt.Errorf("no duration given. use e.g. 1h 30m, 1.5h, 90m or 1:30")
+		}
+		var units = map[byte]float64{'w': float64(schedule.weekLength()), 'd': float64(schedule.dayLength()), 'h': 3600, 'm': 60}
+		var last = -1
+		for rest := text; rest != ""; {
+			var match = durationPart.FindStringSubmatch(rest)
+			if match == nil {
+				return 0, fmt.Errorf("invalid duration %q. use e.g. 1h 30m, 1.5h, 90m or 1:30", input)
+			}
+			rest = strings.TrimLeft(rest[len(match[0]):], " ")
+			value, _ := strconv.ParseFloat(match[1], 64)
+			if match[2] == "" {
+				if rest != "" || last >= 0 {
+					return 0, fmt.Errorf("invalid duration %q. give the unit of each part, e.g. 1h 30m", input)
+				}
+				seconds = value * 60
+				break
+			}
+			var unit = strings.Index(durationUnits, match[2])
+			if unit <= last {
+				return 0, fmt.Errorf("invalid duration %q. give each unit once, from weeks down to minutes, e.g. 1d 2h 30m", input)
+			}
+			last = unit
+			seconds += value * units[match[2][0]]
+		}
+	}
+	if seconds < 60 {
+		return 0, fmt.Errorf("duration %q is less than a minute", input)
+	}
+	return Duration(math.Round(seconds)), nil
+}
+
+// Seconds returns the duration in seconds.
+func (d Duration) Seconds() int {
+	return int(d)
+}
+
+// Hours returns the duration in decimal hours.
+func (d Duration) Hours() float64 {
+	return float64(d) / 3600
+}
+
+// String writes the duration the way Jira does, in hours and minutes rounded to the minute, e.g. 7h 30m.
+func (d Duration) String() string {
+	var minutes = int(math.Round(float64(d) / 60))
+	var sign string
+	if minutes < 0 {
+		sign, minutes = "-", -minutes
+	}
+	var hours = minutes / 60
+	minutes %= 60
+	switch {
+	case hours > 0 && minutes > 0:
+		return fmt.Sprintf("%s%dh %dm", sign, hours, minutes)
+	case hours > 0:
+		return fmt.Sprintf("%s%dh", sign, hours)
+	default:
+		return fmt.Sprintf("%s%dm", sign, minutes)
+	}
+}
+
+// Text writes the duration in the style of duration_format: decimal hours with the verb, e.g. "%.1f" or
+// "%.2fh", or else the way Jira does, with a sign when the verb has one.
+func (d Duration) Text(style string, verb string) string {
+	if style != DurationJira {
+		return fmt.Sprintf(verb, d.Hours())
+	}
+	if strings.Contains(verb, "+") && d >= 0 {
+		return "+" + d.String()
+	}
+	return d.String()
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/praveenprem/timesheet/jira"
+)
+
+/**
+ * Package name: main
+ * Project name: timesheet
+ * Created on: 18/10/2026 10:28
+ */
+
+// Exit codes returned by the application, one per class of failure so scripts can tell them apart.
+const (
+	ExitOK          = 0
+	ExitFailure     = 1
+	ExitUsage       = 2
+	ExitConfig      = 3
+	ExitAuth        = 4
+	ExitNotFound    = 5
+	ExitRateLimited = 6
+	ExitTransport   = 7
+	ExitRejected    = 8
+)
+
+type (
+	// UsageError reports invalid or missing command line arguments.
+	UsageError struct {
+		Msg string
+	}
+
+	// ConfigError reports a missing or malformed configuration.
+	ConfigError struct {
+		Msg string
+		Err error
+	}
+
+	// TicketError reports a ticket time can't be booked on, or a worklog which can't be changed. Err is the
+	// error of Jira when it doesn't know the ticket, which exits with ExitNotFound rather than ExitRejected.
+	TicketError struct {
+		Msg string
+		Err error
+	}
+)
+
+func (e *UsageError) Error() string {
+	return e.Msg
+}
+
+func (e *ConfigError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Msg, e.Err)
+	}
+	return e.Msg
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+func (e *TicketError) Error() string {
+	return e.Msg
+}
+
+func (e *TicketError) Unwrap() error {
+	return e.Err
+}
+
+func usageErrorf(format string, a ...interface{}) error {
+	return &UsageError{Msg: fmt.Sprintf(format, a...)}
+}
+
+// exitCode maps an error returned by the application to the process exit code.
+func exitCode(err error) int {
+	var (
+		usageErr     *UsageError
+		configErr    *ConfigError
+		authErr      *jira.AuthError
+		notFoundErr  *jira.NotFoundError
+		rateLimitErr *jira.RateLimitError
+		transportErr *jira.TransportError
+		ticketErr    *TicketError
+	)
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.As(err, &usageErr):
+		return ExitUsage
+	case errors.As(err, &configErr):
+		return ExitConfig
+	case errors.As(err, &authErr):
+		return ExitAuth
+	case errors.As(err, &notFoundErr):
+		return ExitNotFound
+	case errors.As(err, &rateLimitErr):
+		return ExitRateLimited
+	case errors.As(err, &transportErr), errors.Is(err, context.DeadlineExceeded):
+		return ExitTransport
+	case errors.As(err, &ticketErr):
+		return ExitRejected
+	default:
+		return ExitFailure
+	}
+}
+
+// errorMessage renders an error for the user, with a hint on how to resolve the common failures.
+func errorMessage(err error) string {
+	switch exitCode(err) {
+	case ExitUsage:
+		return fmt.Sprintf("error: %v. try -h for help", err)
+	case ExitConfig:
+		return fmt.Sprintf("configuration error: %v", err)
+	case ExitAuth:
+		return fmt.Sprintf("error: %v. check the email and API token in your configuration", err)
+	case ExitNotFound:
+		return fmt.Sprintf("error: %v", err)
+	case ExitRateLimited:
+		return fmt.Sprintf("error: %v. please try again later", err)
+	case ExitTransport:
+		return fmt.Sprintf("error: %v. check your network connection", err)
+	default:
+		return fmt.Sprintf("error: %v", err)
+	}
+}
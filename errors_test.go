@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/praveenprem/timesheet/jira"
+)
+
+func TestExitCode(t *testing.T) {
+	var notFound = &jira.NotFoundError{Path: "/issue/NOPE-1"}
+	for _, c := range []struct {
+		err     error
+		code    int
+		message string
+	}{
+		{nil, ExitOK, ""},
+		{usageErrorf("no command given"), ExitUsage, "error: no command given. try -h for help"},
+		{&ConfigError{Msg: "profile \"work\" has no domain"}, ExitConfig, "configuration error: profile \"work\" has no domain"},
+		{&jira.AuthError{StatusCode: 401}, ExitAuth, "check the email and API token"},
+		{notFound, ExitNotFound, "error: /issue/NOPE-1 was not found"},
+		{&TicketError{Msg: "issue NOPE-1 doesn't exist", Err: notFound}, ExitNotFound, "error: issue NOPE-1 doesn't exist"},
+		{&TicketError{Msg: "issue DDSP-1 \"Jenkins pipeline\" is Done, time can't be booked on closed issues"}, ExitRejected,
+			"error: issue DDSP-1"},
+		{&jira.RateLimitError{}, ExitRateLimited, "please try again later"},
+		{&jira.TransportError{Err: errors.New("connection refused")}, ExitTransport, "check your network connection"},
+		{fmt.Errorf("looking up DDSP-1: %w", &jira.TransportError{Err: errors.New("timeout")}), ExitTransport, "looking up DDSP-1"},
+		{context.DeadlineExceeded, ExitTransport, "deadline exceeded"},
+		{&jira.APIError{StatusCode: 500}, ExitFailure, "error: request failed (500 Internal Server Error)"},
+		{errors.New("unexpected"), ExitFailure, "error: unexpected"},
+	} {
+		if code := exitCode(c.err); code != c.code {
+			t.Errorf("exitCode(%v) = %d, want %d", c.err, code, c.code)
+		}
+		if c.err != nil && !strings.Contains(errorMessage(c.err), c.message) {
+			t.Errorf("errorMessage(%v) = %q, want it to contain %q", c.err, errorMessage(c.err), c.message)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+/**
+ * Package name: main
+ * Project name: timesheet
+ * Created on: 18/10/2026 11:08
+ */
+
+const (
+	// historySize is the number of recently booked tickets kept in the history.
+	historySize = 50
+	// assignedJQL finds the open issues of the user, offered by the completion next to the history.
+	assignedJQL = "assignee = currentUser() AND statusCategory != Done ORDER BY updated DESC"
+	// assignedMaxAge is how long the assigned issues are kept before they are searched again.
+	assignedMaxAge = time.Hour
+	// assignedSize is the number of assigned issues kept, the most recently updated ones.
+	assignedSize = 100
+	// assignedRetry is how long the completion waits for a refresh it started before starting another.
+	assignedRetry = time.Minute
+)
+
+type (
+	// TicketHistory lists the tickets booked recently, the most recent first.
+	TicketHistory struct {
+		Tickets []RecentTicket `json:"tickets"`
+	}
+
+	// RecentTicket is a ticket of the history. Summary is empty when the issue wasn't known when it was booked.
+	RecentTicket struct {
+		Key     string    `json:"key"`
+		Summary string    `json:"summary,omitempty"`
+		Profile string    `json:"profile,omitempty"`
+		Used    time.Time `json:"used"`
+	}
+
+	// AssignedIssues caches the open issues assigned to the user, searched at most every assignedMaxAge.
+	// Requested is when the completion last started a refresh.
+	AssignedIssues struct {
+		Fetched   time.Time     `json:"fetched"`
+		Requested time.Time     `json:"requested,omitempty"`
+		Issues    []CachedIssue `json:"issues"`
+	}
+)
+
+func historyPath() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", &ConfigError{Msg: "unable to locate the state directory", Err: err}
+	}
+	return filepath.Join(dir, "history.json"), nil
+}
+
+// readHistory returns the ticket history. A missing or unreadable history is returned empty.
+func readHistory() (*TicketHistory, error) {
+	path, err := historyPath()
+	if err != nil {
+		return nil, err
+	}
+	var history TicketHistory
+	if data, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(data, &history); err != nil {
+			history = TicketHistory{}
+		}
+	}
+	return &history, nil
+}
+
+// writeHistory saves the ticket history, replacing the file in one step.
+func writeHistory(history *TicketHistory) error {
+	path, err := historyPath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data, 0600)
+}
+
+// tickets returns the tickets of the profile, the most recent first.
+func (h *TicketHistory) tickets(profile string) []RecentTicket {
+	var tickets []RecentTicket
+	for _, ticket := range h.Tickets {
+		if ticket.Profile == profile {
+			tickets = append(tickets, ticket)
+		}
+	}
+	return tickets
+}
+
+// recentTickets returns the tickets booked recently with the profile followed by the open issues assigned
+// to the user, each once.
+func recentTickets(profile string) []RecentTicket {
+	var tickets []RecentTicket
+	var seen = make(map[string]bool)
+	if history, err := readHistory(); err == nil {
+		for _, ticket := range history.tickets(profile) {
+			if !seen[ticket.Key] {
+				seen[ticket.Key] = true
+				tickets = append(tickets, ticket)
+			}
+		}
+	}
+	if assigned, err := readAssigned(profile); err == nil {
+		for _, issue := range assigned.Issues {
+			if !seen[issue.Key] {
+				seen[issue.Key] = true
+				tickets = append(tickets, RecentTicket{Key: issue.Key, Summary: issue.Summary, Profile: profile})
+			}
+		}
+	}
+	return tickets
+}
+
+// recordTicket moves the ticket to the top of the history of the profile. Without a summary it takes the one
+// the assigned issues or the worklog cache know.
+func (app *App) recordTicket(key string, summary string) {
+	history, err := readHistory()
+	if err != nil {
+		return
+	}
+	if summary == "" {
+		summary = app.knownSummary(key)
+	}
+	var recent = RecentTicket{Key: key, Summary: summary, Profile: app.Configuration.Profile, Used: time.Now()}
+	var tickets = []RecentTicket{recent}
+	for _, ticket := range history.Tickets {
+		if (ticket.Key != recent.Key || ticket.Profile != recent.Profile) && len(tickets) < historySize {
+			tickets = append(tickets, ticket)
+		}
+	}
+	history.Tickets = tickets
+	if err := writeHistory(history); err != nil {
+		fmt.Fprintln(os.Stderr, "unable to save the ticket history:", err)
+	}
+}
+
+// knownSummary returns the summary of the issue from the local caches, empty when they don't have it.
+func (app *App) knownSummary(key string) string {
+	if assigned, err := readAssigned(app.Configuration.Profile); err == nil {
+		for _, issue := range assigned.Issues {
+			if issue.Key == key {
+				return issue.Summary
+			}
+		}
+	}
+	if cache, err := readCache(app.Configuration.Profile); err == nil {
+		for _, issue := range cache.Issues {
+			if issue.Key == key {
+				return issue.Summary
+			}
+		}
+	}
+	return ""
+}
+
+// readAssigned returns the cached assigned issues of the profile. A missing or unreadable cache is returned
+// empty, to be searched again.
+func readAssigned(profile string) (*AssignedIssues, error) {
+	path, err := cacheFile("assigned", profile)
+	if err != nil {
+		return nil, err
+	}
+	var assigned AssignedIssues
+	if data, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(data, &assigned); err != nil {
+			assigned = AssignedIssues{}
+		}
+	}
+	return &assigned, nil
+}
+
+func writeAssigned(profile string, assigned *AssignedIssues) error {
+	path, err := cacheFile("assigned", profile)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(assigned)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data, 0600)
+}
+
+// RefreshAssigned searches the open issues assigned to the user for the completion, which runs it in the
+// background.
+func (app *App) RefreshAssigned(ctx context.Context) error {
+	if err := app.refreshAssigned(ctx); err != nil {
+		return fmt.Errorf("searching the issues assigned to you: %w", err)
+	}
+	return nil
+}
+
+// refreshAssigned searches the open issues assigned to the user when the cached ones are older than
+// assignedMaxAge, so the completion can offer them without waiting for Jira.
+func (app *App) refreshAssigned(ctx context.Context) error {
+	assigned, err := readAssigned(app.Configuration.Profile)
+	if err != nil {
+		return err
+	}
+	if time.Since(assigned.Fetched) < assignedMaxAge {
+		return nil
+	}
+	result, err := app.Client.Search(ctx, assignedJQL)
+	if err != nil {
+		return err
+	}
+	assigned = &AssignedIssues{Fetched: time.Now(), Issues: []CachedIssue{}}
+	for _, issue := range result.Issues {
+		if len(assigned.Issues) == assignedSize {
+			break
+		}
+		assigned.Issues = append(assigned.Issues, cachedIssue(issue))
+	}
+	return writeAssigned(app.Configuration.Profile, assigned)
+}
@@ -0,0 +1,369 @@
+package jira
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+/**
+ * Package name: jira
+ * Project name: timesheet
+ * Created on: 18/10/2026 10:26
+ */
+
+// API is the subset of the Jira REST API used to read and book worklogs.
+type API interface {
+	Myself(ctx context.Context) (*User, error)
+	Search(ctx context.Context, jql string) (*SearchResult, error)
+	Issue(ctx context.Context, issueKey string) (*SearchIssue, error)
+	Worklogs(ctx context.Context, issueKey string, startedAfter time.Time, startedBefore time.Time) (*WorkLogs, error)
+	AddWorklog(ctx context.Context, issueKey string, slot *TimeLog) (*Worklog, error)
+	Worklog(ctx context.Context, issueKey string, id string) (*Worklog, error)
+	UpdateWorklog(ctx context.Context, issueKey string, id string, slot *TimeLog, estimate EstimateAdjustment) (*Worklog, error)
+	DeleteWorklog(ctx context.Context, issueKey string, id string, estimate EstimateAdjustment) error
+	UpdatedWorklogs(ctx context.Context, since time.Time) ([]WorklogChange, time.Time, error)
+	DeletedWorklogs(ctx context.Context, since time.Time) ([]WorklogChange, time.Time, error)
+	WorklogsById(ctx context.Context, ids []int64) ([]Worklog, error)
+}
+
+// EstimateAdjustment tells Jira how to update the remaining estimate of the issue when a worklog is
+// changed. Mode is one of "auto" (default), "leave", "new" (set to NewEstimate) or "manual" (increase
+// by IncreaseBy, deletes only).
+type EstimateAdjustment struct {
+	Mode        string
+	NewEstimate string
+	IncreaseBy  string
+}
+
+// EstimateModes lists the accepted values of EstimateAdjustment.Mode.
+var EstimateModes = []string{"auto", "leave", "new", "manual"}
+
+// Deployment types of Jira.
+const (
+	// Cloud sites use the version 3 REST API with Atlassian document format comments and basic
+	// authentication with an email address and API token.
+	Cloud = "cloud"
+	// Server and Data Center installations use the version 2 REST API with plain text comments and
+	// personal access tokens sent as bearer tokens.
+	Server = "server"
+)
+
+// Timeout is how long NewClient gives a request to be answered, so a network dropping packets fails with a
+// TransportError instead of hanging.
+const Timeout = 30 * time.Second
+
+// Client talks to a single Jira site over HTTP. BaseURL may include a context path, e.g.
+// https://jira.example.com/jira.
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+	Deployment string
+	Email      string
+	Token      string
+}
+
+var _ API = (*Client)(nil)
+
+// NewClient returns a Client for the given Atlassian domain, e.g. xyz.atlassian.net, authenticating
+// with the email:token pair in auth.
+func NewClient(domain string, auth string) *Client {
+	var email, token = splitAuth(auth)
+	return &Client{
+		BaseURL:    fmt.Sprintf("https://%s", strings.TrimSuffix(domain, "\n")),
+		HTTPClient: &http.Client{Timeout: Timeout},
+		Deployment: Cloud,
+		Email:      email,
+		Token:      token,
+	}
+}
+
+// Myself returns the user the client is authenticated as.
+func (c *Client) Myself(ctx context.Context) (*User, error) {
+	var response = new(User)
+	if err := c.do(ctx, "GET", c.api("/myself"), nil, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// searchFields are the issue fields requested by Search.
+const searchFields = "summary,project,parent,components,labels,issuetype"
+
+// Search runs the JQL query and follows the pagination until every matching issue is collected.
+func (c *Client) Search(ctx context.Context, jql string) (*SearchResult, error) {
+	var result SearchResult
+
+	for {
+		var query = url.Values{}
+		query.Set("startAt", fmt.Sprint(result.StartAt))
+		query.Set("maxResults", "50")
+		query.Set("jql", jql)
+		query.Set("fields", searchFields)
+
+		var response = new(SearchResult)
+		if err := c.do(ctx, "GET", c.api("/search?"+query.Encode()), nil, response); err != nil {
+			return nil, err
+		}
+		result.StartAt += response.MaxResults
+		result.MaxResults = response.MaxResults
+		result.Total = response.Total
+		result.Issues = append(result.Issues, response.Issues...)
+		if response.MaxResults == 0 || result.StartAt >= result.Total {
+			break
+		}
+	}
+	return &result, nil
+}
+
+// issueFields are the issue fields requested by Issue.
+const issueFields = "summary,status,project"
+
+// Issue returns the summary, status and project of the issue.
+func (c *Client) Issue(ctx context.Context, issueKey string) (*SearchIssue, error) {
+	var response = new(SearchIssue)
+	if err := c.do(ctx, "GET", c.api(fmt.Sprintf("/issue/%s?fields=%s", issueKey, issueFields)), nil, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// Worklogs returns every worklog on the issue started within the window, following the pagination.
+// A zero startedAfter or startedBefore leaves that side of the window open.
+func (c *Client) Worklogs(ctx context.Context, issueKey string, startedAfter time.Time, startedBefore time.Time) (*WorkLogs, error) {
+	var result = WorkLogs{Key: issueKey}
+
+	for {
+		var query = url.Values{}
+		query.Set("startAt", fmt.Sprint(result.StartAt))
+		if !startedAfter.IsZero() {
+			query.Set("startedAfter", fmt.Sprint(startedAfter.UnixNano()/int64(time.Millisecond)))
+		}
+		if !startedBefore.IsZero() {
+			query.Set("startedBefore", fmt.Sprint(startedBefore.UnixNano()/int64(time.Millisecond)))
+		}
+
+		var response = new(WorkLogs)
+		if err := c.do(ctx, "GET", c.api(fmt.Sprintf("/issue/%s/worklog?%s", issueKey, query.Encode())), nil, response); err != nil {
+			return nil, err
+		}
+		result.StartAt += len(response.Worklogs)
+		result.MaxResults = response.MaxResults
+		result.Total = response.Total
+		result.Worklogs = append(result.Worklogs, response.Worklogs...)
+		if len(response.Worklogs) == 0 || result.StartAt >= result.Total {
+			break
+		}
+	}
+	result.StartAt = 0
+	return &result, nil
+}
+
+// AddWorklog books the time slot against the issue and returns the worklog Jira created.
+func (c *Client) AddWorklog(ctx context.Context, issueKey string, slot *TimeLog) (*Worklog, error) {
+	body, err := c.worklogBody(slot)
+	if err != nil {
+		return nil, err
+	}
+
+	var response = new(Worklog)
+	if err := c.do(ctx, "POST", c.api(fmt.Sprintf("/issue/%s/worklog", issueKey)), bytes.NewBuffer(body), response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// Worklog returns a single worklog of the issue.
+func (c *Client) Worklog(ctx context.Context, issueKey string, id string) (*Worklog, error) {
+	var response = new(Worklog)
+	if err := c.do(ctx, "GET", c.api(fmt.Sprintf("/issue/%s/worklog/%s", issueKey, id)), nil, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// UpdateWorklog changes the fields set on slot of an existing worklog.
+func (c *Client) UpdateWorklog(ctx context.Context, issueKey string, id string, slot *TimeLog, estimate EstimateAdjustment) (*Worklog, error) {
+	body, err := c.worklogBody(slot)
+	if err != nil {
+		return nil, err
+	}
+
+	var response = new(Worklog)
+	var path = c.api(fmt.Sprintf("/issue/%s/worklog/%s%s", issueKey, id, estimate.query(false)))
+	if err := c.do(ctx, "PUT", path, bytes.NewBuffer(body), response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// DeleteWorklog removes a worklog from the issue.
+func (c *Client) DeleteWorklog(ctx context.Context, issueKey string, id string, estimate EstimateAdjustment) error {
+	var path = c.api(fmt.Sprintf("/issue/%s/worklog/%s%s", issueKey, id, estimate.query(true)))
+	return c.do(ctx, "DELETE", path, nil, nil)
+}
+
+// UpdatedWorklogs returns the IDs of the worklogs created or updated since the given time, across every
+// issue of the site, following the pagination. The returned time is where the feed ends and is meant to be
+// the since of the next call. Jira leaves out the changes of the last minute.
+func (c *Client) UpdatedWorklogs(ctx context.Context, since time.Time) ([]WorklogChange, time.Time, error) {
+	return c.worklogChanges(ctx, "/worklog/updated", since)
+}
+
+// DeletedWorklogs returns the IDs of the worklogs deleted since the given time, the same way as
+// UpdatedWorklogs.
+func (c *Client) DeletedWorklogs(ctx context.Context, since time.Time) ([]WorklogChange, time.Time, error) {
+	return c.worklogChanges(ctx, "/worklog/deleted", since)
+}
+
+func (c *Client) worklogChanges(ctx context.Context, feed string, since time.Time) ([]WorklogChange, time.Time, error) {
+	var changes []WorklogChange
+	var from int64
+	if !since.IsZero() {
+		from = since.UnixNano() / int64(time.Millisecond)
+	}
+
+	for {
+		var response = new(WorklogChanges)
+		if err := c.do(ctx, "GET", c.api(fmt.Sprintf("%s?since=%d", feed, from)), nil, response); err != nil {
+			return nil, time.Time{}, err
+		}
+		changes = append(changes, response.Values...)
+		if response.Until > from {
+			from = response.Until
+		}
+		if response.LastPage || len(response.Values) == 0 {
+			break
+		}
+	}
+	return changes, time.Unix(0, from*int64(time.Millisecond)), nil
+}
+
+// WorklogsById returns the worklogs with the given IDs, whatever issue they are on. IDs which don't exist
+// or aren't visible to the user are left out.
+func (c *Client) WorklogsById(ctx context.Context, ids []int64) ([]Worklog, error) {
+	const batch = 1000
+	var worklogs []Worklog
+
+	for start := 0; start < len(ids); start += batch {
+		var end = start + batch
+		if end > len(ids) {
+			end = len(ids)
+		}
+		body, err := json.Marshal(map[string][]int64{"ids": ids[start:end]})
+		if err != nil {
+			return nil, err
+		}
+
+		var response []Worklog
+		if err := c.do(ctx, "POST", c.api("/worklog/list"), bytes.NewBuffer(body), &response); err != nil {
+			return nil, err
+		}
+		worklogs = append(worklogs, response...)
+	}
+	return worklogs, nil
+}
+
+func (e EstimateAdjustment) query(deleting bool) string {
+	var query = url.Values{}
+	if e.Mode != "" {
+		query.Set("adjustEstimate", e.Mode)
+	}
+	if e.Mode == "new" && e.NewEstimate != "" {
+		query.Set("newEstimate", e.NewEstimate)
+	}
+	if deleting && e.Mode == "manual" && e.IncreaseBy != "" {
+		query.Set("increaseBy", e.IncreaseBy)
+	}
+	if len(query) == 0 {
+		return ""
+	}
+	return "?" + query.Encode()
+}
+
+// api returns the path of the endpoint in the REST API version of the deployment.
+func (c *Client) api(path string) string {
+	if c.Deployment == Server {
+		return "/rest/api/2" + path
+	}
+	return "/rest/api/3" + path
+}
+
+// worklogBody encodes the time slot, with the comment as plain text for Server deployments.
+func (c *Client) worklogBody(slot *TimeLog) ([]byte, error) {
+	if c.Deployment != Server {
+		return json.Marshal(slot)
+	}
+	var body = struct {
+		Started          string `json:"started,omitempty"`
+		TimeSpent        string `json:"timeSpent,omitempty"`
+		TimeSpentSeconds int    `json:"timeSpentSeconds,omitempty"`
+		Comment          string `json:"comment,omitempty"`
+	}{
+		Started:          slot.Started,
+		TimeSpent:        slot.TimeSpent,
+		TimeSpentSeconds: slot.TimeSpentSeconds,
+		Comment:          slot.Comment.Text(),
+	}
+	return json.Marshal(body)
+}
+
+func (c *Client) newRequest(ctx context.Context, method string, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.BaseURL, "/")+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Content-Type", "application/json")
+	if c.Deployment == Server && c.Email == "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	} else {
+		req.SetBasicAuth(c.Email, c.Token)
+	}
+	return req, nil
+}
+
+// do sends the request and decodes a successful response into out. Unsuccessful responses are turned
+// into AuthError, NotFoundError, RateLimitError or APIError; failures to talk to Jira into TransportError.
+func (c *Client) do(ctx context.Context, method string, path string, body io.Reader, out interface{}) error {
+	req, reqErr := c.newRequest(ctx, method, path, body)
+	if reqErr != nil {
+		return reqErr
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return &TransportError{Err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var response Response
+		_ = json.NewDecoder(resp.Body).Decode(&response)
+		return statusError(resp, path, response.ErrorMessages)
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if decodeErr := json.NewDecoder(resp.Body).Decode(out); decodeErr != nil {
+		return &TransportError{Err: fmt.Errorf("decoding response of %s %s: %v", method, path, decodeErr)}
+	}
+	return nil
+}
+
+func splitAuth(auth string) (string, string) {
+	var loginDetails = strings.SplitN(strings.TrimSpace(auth), ":", 2)
+	if len(loginDetails) < 2 {
+		return loginDetails[0], ""
+	}
+	return loginDetails[0], loginDetails[1]
+}
@@ -0,0 +1,308 @@
+package jira_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/praveenprem/timesheet/jira"
+	"github.com/praveenprem/timesheet/jira/jiratest"
+)
+
+var day = time.Date(2020, 3, 4, 9, 0, 0, 0, time.UTC)
+
+func newServer(t *testing.T) *jiratest.Server {
+	var server = jiratest.NewServer("dev@example.com", "secret")
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestSearchFollowsPagination(t *testing.T) {
+	var server = newServer(t)
+	server.PageSize = 2
+	for i := 1; i <= 5; i++ {
+		var key = fmt.Sprintf("DDSP-%d", i)
+		server.AddIssue(key, "Issue "+key)
+		server.AddWorklog(key, "dev@example.com", day, 3600, "")
+	}
+
+	result, err := server.Client().Search(context.Background(), `worklogDate >= "2020-03-04" AND worklogDate <= "2020-03-04"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Issues) != 5 || result.Total != 5 {
+		t.Fatalf("got %d issues of %d, want 5", len(result.Issues), result.Total)
+	}
+	if result.Issues[4].Key != "DDSP-5" || result.Issues[4].Fields.Summary != "Issue DDSP-5" {
+		t.Errorf("last issue = %+v", result.Issues[4])
+	}
+	if project := result.Issues[4].Fields.Project; project == nil || project.Key != "DDSP" {
+		t.Errorf("project of the last issue = %+v", project)
+	}
+	if request := server.Requests()[0]; !strings.Contains(request, "fields=summary%2Cproject%2Cparent") {
+		t.Errorf("search request %s doesn't ask for the grouping fields", request)
+	}
+}
+
+func TestIssue(t *testing.T) {
+	var server = newServer(t)
+	server.AddIssue("DDSP-1", "Jenkins pipeline")
+	server.SetFields("DDSP-1", jira.IssueFields{Status: &jira.Status{Name: "Done", StatusCategory: jira.StatusCategory{Key: "done"}}})
+
+	issue, err := server.Client().Issue(context.Background(), "DDSP-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issue.Fields.Summary != "Jenkins pipeline" || issue.Fields.Status == nil || issue.Fields.Status.Name != "Done" ||
+		issue.Fields.Project == nil || issue.Fields.Project.Key != "DDSP" {
+		t.Errorf("issue = %+v", issue)
+	}
+	if request := server.Requests()[0]; !strings.Contains(request, "fields=summary,status,project") {
+		t.Errorf("issue request %s doesn't ask for the status", request)
+	}
+
+	var notFound *jira.NotFoundError
+	if _, err := server.Client().Issue(context.Background(), "DDSP-9"); !errors.As(err, &notFound) {
+		t.Errorf("unknown issue returned %v, want a NotFoundError", err)
+	}
+}
+
+func TestWorklogsFollowsPaginationWithinWindow(t *testing.T) {
+	var server = newServer(t)
+	server.PageSize = 20
+	server.AddIssue("DDSP-1", "Meetings")
+	for i := 0; i < 45; i++ {
+		server.AddWorklog("DDSP-1", "someone@example.com", day.AddDate(0, 0, -i-1), 1800, "")
+	}
+	server.AddWorklog("DDSP-1", "dev@example.com", day, 3600, "stand-up")
+
+	var after, before = day.Add(-time.Hour), day.Add(time.Hour)
+	worklogs, err := server.Client().Worklogs(context.Background(), "DDSP-1", after, before)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(worklogs.Worklogs) != 1 || worklogs.Worklogs[0].Comment.Text() != "stand-up" {
+		t.Fatalf("got %+v, want only the stand-up worklog", worklogs.Worklogs)
+	}
+
+	all, err := server.Client().Worklogs(context.Background(), "DDSP-1", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all.Worklogs) != 46 {
+		t.Fatalf("got %d worklogs, want 46", len(all.Worklogs))
+	}
+}
+
+func TestAddUpdateAndDeleteWorklog(t *testing.T) {
+	var server = newServer(t)
+	server.AddIssue("DDSP-1", "Pipeline")
+	var client = server.Client()
+	var ctx = context.Background()
+
+	created, err := client.AddWorklog(ctx, "DDSP-1", &jira.TimeLog{
+		Started:   "2020-03-04T09:00:00.000+0000",
+		TimeSpent: "1h 30m",
+		Comment:   jira.NewComment("pipeline"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Id == "" || created.TimeSpentSeconds != 5400 {
+		t.Fatalf("created = %+v", created)
+	}
+
+	updated, err := client.UpdateWorklog(ctx, "DDSP-1", created.Id, &jira.TimeLog{TimeSpent: "2h"},
+		jira.EstimateAdjustment{Mode: "leave"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.TimeSpentSeconds != 7200 || updated.Comment.Text() != "pipeline" {
+		t.Errorf("updated = %+v", updated)
+	}
+
+	if err := client.DeleteWorklog(ctx, "DDSP-1", created.Id, jira.EstimateAdjustment{Mode: "manual", IncreaseBy: "2h"}); err != nil {
+		t.Fatal(err)
+	}
+	if logs := server.Worklogs("DDSP-1"); len(logs) != 0 {
+		t.Errorf("worklogs left after delete: %+v", logs)
+	}
+
+	var requests = server.Requests()
+	if last := requests[len(requests)-1]; last != "DELETE /rest/api/3/issue/DDSP-1/worklog/"+created.Id+"?adjustEstimate=manual&increaseBy=2h" {
+		t.Errorf("delete request = %s", last)
+	}
+}
+
+func TestCommentText(t *testing.T) {
+	for document, want := range map[string]string{
+		`null`:             "",
+		`"Server comment"`: "Server comment",
+		`{"type": "doc", "version": 1, "content": []}`: "",
+		`{"type": "doc", "version": 1, "content": [{"type": "paragraph", "content": [{"type": "text", "text": "stand-up"}]}]}`: "stand-up",
+		`{"type": "doc", "version": 1, "content": [
+			{"type": "paragraph", "content": [{"type": "text", "text": "Pipeline "}, {"type": "text", "text": "fixed", "marks": [{"type": "strong"}]}]},
+			{"type": "paragraph", "content": [{"type": "text", "text": "line one"}, {"type": "hardBreak"}, {"type": "text", "text": "line two"}]},
+			{"type": "bulletList", "content": [
+				{"type": "listItem", "content": [{"type": "paragraph", "content": [{"type": "text", "text": "first"}]}]},
+				{"type": "listItem", "content": [{"type": "paragraph", "content": [{"type": "text", "text": "second"}]}]}]}]}`: "Pipeline fixed\nline one\nline two\nfirst\nsecond",
+	} {
+		var worklog jira.Worklog
+		if err := json.Unmarshal([]byte(`{"comment": `+document+`}`), &worklog); err != nil {
+			t.Fatal(err)
+		}
+		if text := worklog.Comment.Text(); text != want {
+			t.Errorf("Text of %s = %q, want %q", document, text, want)
+		}
+	}
+}
+
+func TestErrorTypes(t *testing.T) {
+	var server = newServer(t)
+	var ctx = context.Background()
+
+	var wrongToken = jira.NewClient("", "dev@example.com:wrong")
+	wrongToken.BaseURL = server.URL
+	var authErr *jira.AuthError
+	if _, err := wrongToken.Search(ctx, ""); !errors.As(err, &authErr) {
+		t.Errorf("wrong token: got %v, want AuthError", err)
+	}
+
+	var notFoundErr *jira.NotFoundError
+	if _, err := server.Client().Worklogs(ctx, "NOPE-1", time.Time{}, time.Time{}); !errors.As(err, &notFoundErr) {
+		t.Errorf("unknown issue: got %v, want NotFoundError", err)
+	} else if notFoundErr.Error() != "Issue does not exist or you do not have permission to see it." {
+		t.Errorf("unknown issue message = %q", notFoundErr.Error())
+	}
+
+	var throttled = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer throttled.Close()
+	var client = jira.NewClient("", "dev@example.com:secret")
+	client.BaseURL = throttled.URL
+	var rateLimitErr *jira.RateLimitError
+	if _, err := client.Search(ctx, ""); !errors.As(err, &rateLimitErr) || rateLimitErr.RetryAfter != 30*time.Second {
+		t.Errorf("throttled: got %v, want RateLimitError retrying after 30s", err)
+	}
+
+	var unreachable = jira.NewClient("", "dev@example.com:secret")
+	unreachable.BaseURL = "http://127.0.0.1:1"
+	var transportErr *jira.TransportError
+	if _, err := unreachable.Search(ctx, ""); !errors.As(err, &transportErr) {
+		t.Errorf("unreachable: got %v, want TransportError", err)
+	}
+}
+
+func TestHangingRequestsTimeOut(t *testing.T) {
+	var hang = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	t.Cleanup(hang.Close)
+
+	var client = jira.NewClient("example.atlassian.net", "dev@example.com:secret")
+	if client.HTTPClient.Timeout != jira.Timeout {
+		t.Errorf("timeout = %v, want %v", client.HTTPClient.Timeout, jira.Timeout)
+	}
+	client.BaseURL = hang.URL
+	client.HTTPClient.Timeout = 50 * time.Millisecond
+	var transportErr *jira.TransportError
+	if _, err := client.Myself(context.Background()); !errors.As(err, &transportErr) {
+		t.Errorf("hanging request = %v, want a transport error", err)
+	}
+}
+
+func TestServerDeployment(t *testing.T) {
+	var server = newServer(t)
+	server.Deployment = jira.Server
+	server.ContextPath = "/jira"
+	server.AddIssue("DDSP-1", "Pipeline")
+	var client = server.Client()
+	var ctx = context.Background()
+
+	me, err := client.Myself(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if me.Name != "dev" || me.AccountId != "" {
+		t.Errorf("myself = %+v", me)
+	}
+
+	if _, err := client.AddWorklog(ctx, "DDSP-1", &jira.TimeLog{
+		Started:   "2020-03-04T09:00:00.000+0000",
+		TimeSpent: "1h",
+		Comment:   jira.NewComment("pipeline"),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	worklogs, err := client.Worklogs(ctx, "DDSP-1", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(worklogs.Worklogs) != 1 || worklogs.Worklogs[0].Comment.Text() != "pipeline" || worklogs.Worklogs[0].Author.Name != "dev" {
+		t.Errorf("worklogs = %+v", worklogs.Worklogs)
+	}
+
+	for _, request := range server.Requests() {
+		if !strings.Contains(request, " /jira/rest/api/2/") {
+			t.Errorf("request %s is not for the version 2 API under the context path", request)
+		}
+	}
+
+	var basic = jira.NewClient("", "dev@example.com:wrong")
+	basic.BaseURL = server.URL + "/jira"
+	basic.Deployment = jira.Server
+	if _, err := basic.Myself(ctx); err == nil {
+		t.Error("wrong credentials were accepted")
+	}
+}
+
+func TestWorklogChangeFeeds(t *testing.T) {
+	var server = newServer(t)
+	server.PageSize = 2
+	server.AddIssue("DDSP-1", "Meetings")
+	var since = time.Now().Add(-time.Minute)
+	var ids []string
+	for i := 0; i < 5; i++ {
+		ids = append(ids, server.AddWorklog("DDSP-1", "dev@example.com", day.Add(time.Duration(i)*time.Hour), 1800, fmt.Sprint(i)))
+	}
+	var client = server.Client()
+	var ctx = context.Background()
+	if err := client.DeleteWorklog(ctx, "DDSP-1", ids[0], jira.EstimateAdjustment{}); err != nil {
+		t.Fatal(err)
+	}
+
+	updated, until, err := client.UpdatedWorklogs(ctx, since)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updated) != 5 || fmt.Sprint(updated[4].WorklogId) != ids[4] || !until.After(since) {
+		t.Errorf("updated feed = %+v until %s", updated, until)
+	}
+	deleted, _, err := client.DeletedWorklogs(ctx, since)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted) != 1 || fmt.Sprint(deleted[0].WorklogId) != ids[0] {
+		t.Errorf("deleted feed = %+v", deleted)
+	}
+	if later, _, err := client.UpdatedWorklogs(ctx, until); err != nil || len(later) != 0 {
+		t.Errorf("updated feed after %s = %+v, %v", until, later, err)
+	}
+
+	worklogs, err := client.WorklogsById(ctx, []int64{updated[0].WorklogId, updated[3].WorklogId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(worklogs) != 1 || worklogs[0].Id != ids[3] || worklogs[0].IssueId == "" || worklogs[0].Comment.Text() != "3" {
+		t.Errorf("worklogs by id = %+v", worklogs)
+	}
+}
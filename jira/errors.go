@@ -0,0 +1,101 @@
+package jira
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/**
+ * Package name: jira
+ * Project name: timesheet
+ * Created on: 18/10/2026 10:28
+ */
+
+type (
+	// AuthError is returned when Jira rejects the credentials (HTTP 401 or 403).
+	AuthError struct {
+		StatusCode int
+		Messages   []string
+	}
+
+	// NotFoundError is returned when the requested resource does not exist or is not visible to the user.
+	NotFoundError struct {
+		Path     string
+		Messages []string
+	}
+
+	// RateLimitError is returned when Jira throttles the client. RetryAfter is zero when the server
+	// didn't say how long to wait.
+	RateLimitError struct {
+		RetryAfter time.Duration
+	}
+
+	// TransportError wraps failures to reach Jira or to read its response.
+	TransportError struct {
+		Err error
+	}
+
+	// APIError is returned for any other unsuccessful response.
+	APIError struct {
+		StatusCode int
+		Messages   []string
+	}
+)
+
+func (e *AuthError) Error() string {
+	return withMessages(fmt.Sprintf("authentication failed (%d %s)", e.StatusCode, http.StatusText(e.StatusCode)), e.Messages)
+}
+
+func (e *NotFoundError) Error() string {
+	if len(e.Messages) > 0 {
+		return strings.Join(e.Messages, " ")
+	}
+	return fmt.Sprintf("%s was not found", e.Path)
+}
+
+func (e *RateLimitError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("rate limited by Jira, retry after %s", e.RetryAfter)
+	}
+	return "rate limited by Jira"
+}
+
+func (e *TransportError) Error() string {
+	return fmt.Sprintf("unable to reach Jira: %v", e.Err)
+}
+
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
+
+func (e *APIError) Error() string {
+	return withMessages(fmt.Sprintf("request failed (%d %s)", e.StatusCode, http.StatusText(e.StatusCode)), e.Messages)
+}
+
+// statusError maps an unsuccessful response to one of the error types above.
+func statusError(resp *http.Response, path string, messages []string) error {
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return &AuthError{StatusCode: resp.StatusCode, Messages: messages}
+	case resp.StatusCode == http.StatusNotFound:
+		return &NotFoundError{Path: path, Messages: messages}
+	case resp.StatusCode == http.StatusTooManyRequests:
+		var retryAfter time.Duration
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			retryAfter = time.Duration(seconds) * time.Second
+		}
+		return &RateLimitError{RetryAfter: retryAfter}
+	default:
+		return &APIError{StatusCode: resp.StatusCode, Messages: messages}
+	}
+}
+
+func withMessages(msg string, messages []string) string {
+	if len(messages) == 0 {
+		return msg
+	}
+	return fmt.Sprintf("%s: %s", msg, strings.Join(messages, " "))
+}
@@ -0,0 +1,574 @@
+// Package jiratest provides an in-memory Jira server for tests, emulating the search and worklog
+// endpoints of the REST API.
+package jiratest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/praveenprem/timesheet/jira"
+)
+
+/**
+ * Package name: jiratest
+ * Project name: timesheet
+ * Created on: 18/10/2026 10:35
+ */
+
+// StartedFormat is the layout of worklog start times used by Jira.
+const StartedFormat = "2006-01-02T15:04:05.000-0700"
+
+var (
+	worklogDateJQL = regexp.MustCompile(`worklogDate >= "([0-9-]+)" AND worklogDate <= "([0-9-]+)"`)
+	issueIdJQL     = regexp.MustCompile(`^id in \(([0-9, ]+)\)$`)
+	ownUpdatedJQL  = regexp.MustCompile(`^worklogAuthor = currentUser\(\) AND updated >= "([0-9-]+)"$`)
+	worklogPath    = regexp.MustCompile(`^/issue/([^/]+)/worklog(?:/([^/]+))?$`)
+	issuePath      = regexp.MustCompile(`^/issue/([^/]+)$`)
+)
+
+type (
+	// Server is a fake Jira site. Issues and worklogs can be seeded before, and inspected after, the code
+	// under test talked to it.
+	Server struct {
+		*httptest.Server
+		Email string
+		Token string
+		// PageSize limits the number of issues and worklogs returned per page, to exercise pagination.
+		PageSize int
+		// HideEmails leaves the email address out of users, as Cloud sites do depending on the profile
+		// visibility settings.
+		HideEmails bool
+		// Deployment switches the server between the Cloud API (jira.Cloud, the default) and the
+		// Server/Data Center API (jira.Server) with plain text comments and bearer tokens.
+		Deployment string
+		// ContextPath is the path prefix the site is served under, e.g. /jira.
+		ContextPath string
+
+		mu       sync.Mutex
+		issues   []*issue
+		nextId   int
+		requests []string
+		updated  []jira.WorklogChange
+		deleted  []jira.WorklogChange
+		clock    int64
+	}
+
+	issue struct {
+		id       string
+		key      string
+		fields   jira.IssueFields
+		worklogs []jira.Worklog
+	}
+)
+
+// NewServer starts a fake Jira site accepting the given credentials. Close it when done.
+func NewServer(email string, token string) *Server {
+	var s = &Server{Email: email, Token: token, PageSize: 50, nextId: 10000}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Client returns a jira.Client talking to the server with its credentials, using a bearer token for
+// Server deployments.
+func (s *Server) Client() *jira.Client {
+	var client = jira.NewClient("", fmt.Sprintf("%s:%s", s.Email, s.Token))
+	client.BaseURL = s.URL + s.ContextPath
+	client.HTTPClient = s.Server.Client()
+	if s.Deployment == jira.Server {
+		client.Deployment = jira.Server
+		client.Email = ""
+	}
+	return client
+}
+
+// AddIssue creates an issue, in the To Do status.
+func (s *Server) AddIssue(key string, summary string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextId++
+	var fields = jira.IssueFields{
+		Summary: summary,
+		Project: &jira.Project{Key: strings.SplitN(key, "-", 2)[0]},
+		Status:  &jira.Status{Name: "To Do", StatusCategory: jira.StatusCategory{Key: "new", Name: "To Do"}},
+	}
+	s.issues = append(s.issues, &issue{id: strconv.Itoa(s.nextId), key: key, fields: fields})
+}
+
+// SetFields replaces the fields of an existing issue returned by the search, keeping its summary, project
+// and status when fields leaves them out.
+func (s *Server) SetFields(key string, fields jira.IssueFields) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var found = s.issue(key)
+	if found == nil {
+		panic(fmt.Sprintf("jiratest: no issue %s", key))
+	}
+	if fields.Summary == "" {
+		fields.Summary = found.fields.Summary
+	}
+	if fields.Project == nil {
+		fields.Project = found.fields.Project
+	}
+	if fields.Status == nil {
+		fields.Status = found.fields.Status
+	}
+	found.fields = fields
+}
+
+// AddWorklog books time on an existing issue as the given author and returns the worklog ID.
+func (s *Server) AddWorklog(key string, author string, started time.Time, seconds int, comment string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var found = s.issue(key)
+	if found == nil {
+		panic(fmt.Sprintf("jiratest: no issue %s", key))
+	}
+	return s.addWorklog(found, author, started.Format(StartedFormat), seconds, jira.NewComment(comment))
+}
+
+// Worklogs returns the worklogs currently on the issue.
+func (s *Server) Worklogs(key string) []jira.Worklog {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if found := s.issue(key); found != nil {
+		return append([]jira.Worklog(nil), found.worklogs...)
+	}
+	return nil
+}
+
+// AccountId returns the account ID the server gives the user with the email address.
+func AccountId(email string) string {
+	return fmt.Sprintf("acc-%s", UserName(email))
+}
+
+// UserName returns the user name a Server deployment gives the user with the email address.
+func UserName(email string) string {
+	return strings.SplitN(email, "@", 2)[0]
+}
+
+// Requests returns the method and URI of every request received, in order.
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+func (s *Server) issue(key string) *issue {
+	for _, i := range s.issues {
+		if i.key == key || i.id == key {
+			return i
+		}
+	}
+	return nil
+}
+
+func (s *Server) addWorklog(i *issue, author string, started string, seconds int, comment *jira.Comment) string {
+	s.nextId++
+	var log = jira.Worklog{
+		Id:               strconv.Itoa(s.nextId),
+		IssueId:          i.id,
+		Started:          started,
+		TimeSpentSeconds: seconds,
+		Comment:          comment,
+	}
+	log.Author = jira.User{AccountId: AccountId(author), Name: UserName(author), EmailAddress: author, DisplayName: author}
+	i.worklogs = append(i.worklogs, log)
+	s.touch(&i.worklogs[len(i.worklogs)-1])
+	return log.Id
+}
+
+// touch records a change of the worklog in the updated feed.
+func (s *Server) touch(log *jira.Worklog) {
+	var now = s.now()
+	log.Updated = time.Unix(0, now*int64(time.Millisecond)).UTC().Format(StartedFormat)
+	id, _ := strconv.ParseInt(log.Id, 10, 64)
+	s.updated = append(s.updated, jira.WorklogChange{WorklogId: id, UpdatedTime: now})
+}
+
+// now returns the current time in milliseconds, moving forward on every call so each change has its own
+// timestamp in the feeds.
+func (s *Server) now() int64 {
+	var now = time.Now().UnixNano() / int64(time.Millisecond)
+	if now <= s.clock {
+		now = s.clock + 1
+	}
+	s.clock = now
+	return now
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, fmt.Sprintf("%s %s", r.Method, r.URL.RequestURI()))
+
+	if !s.authenticated(r) {
+		writeError(w, http.StatusUnauthorized, "Client must be authenticated to access this resource.")
+		return
+	}
+
+	var api = "/rest/api/3"
+	if s.Deployment == jira.Server {
+		api = "/rest/api/2"
+	}
+	var path = strings.TrimPrefix(r.URL.Path, s.ContextPath+api)
+	if path == r.URL.Path {
+		writeError(w, http.StatusNotFound, "Not found")
+		return
+	}
+
+	if path == "/myself" && r.Method == "GET" {
+		var me = jira.User{AccountId: AccountId(s.Email), Name: UserName(s.Email), EmailAddress: s.Email, DisplayName: s.Email}
+		writeJSON(w, http.StatusOK, s.user(me))
+		return
+	}
+
+	if path == "/search" && r.Method == "GET" {
+		s.search(w, r)
+		return
+	}
+
+	if path == "/worklog/updated" && r.Method == "GET" {
+		s.worklogChanges(w, r, s.updated)
+		return
+	}
+
+	if path == "/worklog/deleted" && r.Method == "GET" {
+		s.worklogChanges(w, r, s.deleted)
+		return
+	}
+
+	if path == "/worklog/list" && r.Method == "POST" {
+		s.worklogsById(w, r)
+		return
+	}
+
+	if match := issuePath.FindStringSubmatch(path); match != nil && r.Method == "GET" {
+		s.getIssue(w, match[1])
+		return
+	}
+
+	var match = worklogPath.FindStringSubmatch(path)
+	if match == nil {
+		writeError(w, http.StatusNotFound, "Not found")
+		return
+	}
+	var found = s.issue(match[1])
+	if found == nil {
+		writeError(w, http.StatusNotFound, "Issue does not exist or you do not have permission to see it.")
+		return
+	}
+
+	switch {
+	case match[2] == "" && r.Method == "GET":
+		s.listWorklogs(w, r, found)
+	case match[2] == "" && r.Method == "POST":
+		s.createWorklog(w, r, found)
+	case match[2] != "":
+		s.worklog(w, r, found, match[2])
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// getIssue serves an issue with all of its fields, whichever were asked for.
+func (s *Server) getIssue(w http.ResponseWriter, key string) {
+	var found = s.issue(key)
+	if found == nil {
+		writeError(w, http.StatusNotFound, "Issue does not exist or you do not have permission to see it.")
+		return
+	}
+	writeJSON(w, http.StatusOK, jira.SearchIssue{Id: found.id, Key: found.key, Fields: found.fields})
+}
+
+func (s *Server) search(w http.ResponseWriter, r *http.Request) {
+	var matching []*issue
+	var jql = r.URL.Query().Get("jql")
+	var dates = worklogDateJQL.FindStringSubmatch(jql)
+	var ids = issueIdJQL.FindStringSubmatch(jql)
+	var own = ownUpdatedJQL.FindStringSubmatch(jql)
+	for _, i := range s.issues {
+		if own != nil {
+			// The issue counts as updated when a worklog on it changed.
+			var authored, updated bool
+			for _, log := range i.worklogs {
+				authored = authored || strings.EqualFold(log.Author.EmailAddress, s.Email)
+				updated = updated || log.Updated[:10] >= own[1]
+			}
+			if authored && updated {
+				matching = append(matching, i)
+			}
+			continue
+		}
+		if ids != nil {
+			for _, id := range strings.Split(ids[1], ",") {
+				if strings.TrimSpace(id) == i.id {
+					matching = append(matching, i)
+				}
+			}
+			continue
+		}
+		if dates == nil {
+			matching = append(matching, i)
+			continue
+		}
+		for _, log := range i.worklogs {
+			if day := log.Started[:10]; day >= dates[1] && day <= dates[2] {
+				matching = append(matching, i)
+				break
+			}
+		}
+	}
+
+	var startAt, _ = strconv.Atoi(r.URL.Query().Get("startAt"))
+	var maxResults = s.pageSize(r)
+	var result = jira.SearchResult{StartAt: startAt, MaxResults: maxResults, Total: len(matching)}
+	for n := startAt; n < len(matching) && n < startAt+maxResults; n++ {
+		var found jira.SearchIssue
+		found.Id = matching[n].id
+		found.Key = matching[n].key
+		found.Fields = matching[n].fields
+		result.Issues = append(result.Issues, found)
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// worklogChanges serves a page of the updated or deleted feed, with the changes made after since.
+func (s *Server) worklogChanges(w http.ResponseWriter, r *http.Request, feed []jira.WorklogChange) {
+	var since, _ = strconv.ParseInt(r.URL.Query().Get("since"), 10, 64)
+	var page = jira.WorklogChanges{Values: []jira.WorklogChange{}, Since: since, Until: since, LastPage: true}
+	for _, change := range feed {
+		if change.UpdatedTime <= since {
+			continue
+		}
+		if len(page.Values) == s.PageSize {
+			page.LastPage = false
+			break
+		}
+		page.Values = append(page.Values, change)
+		page.Until = change.UpdatedTime
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (s *Server) worklogsById(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Ids []int64 `json:"ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var found = []interface{}{}
+	for _, id := range request.Ids {
+		for _, i := range s.issues {
+			for _, log := range i.worklogs {
+				if log.Id == strconv.FormatInt(id, 10) {
+					found = append(found, s.worklogView(log))
+				}
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, found)
+}
+
+func (s *Server) listWorklogs(w http.ResponseWriter, r *http.Request, i *issue) {
+	var after, before = millis(r.URL.Query().Get("startedAfter")), millis(r.URL.Query().Get("startedBefore"))
+	var matching []jira.Worklog
+	for _, log := range i.worklogs {
+		started, err := time.Parse(StartedFormat, log.Started)
+		if err != nil {
+			continue
+		}
+		if (!after.IsZero() && started.Before(after)) || (!before.IsZero() && !started.Before(before)) {
+			continue
+		}
+		matching = append(matching, log)
+	}
+	sort.SliceStable(matching, func(a, b int) bool { return matching[a].Started < matching[b].Started })
+
+	var startAt, _ = strconv.Atoi(r.URL.Query().Get("startAt"))
+	var maxResults = s.pageSize(r)
+	var page = []interface{}{}
+	for n := startAt; n < len(matching) && n < startAt+maxResults; n++ {
+		page = append(page, s.worklogView(matching[n]))
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"startAt":    startAt,
+		"maxResults": maxResults,
+		"total":      len(matching),
+		"worklogs":   page,
+	})
+}
+
+func (s *Server) createWorklog(w http.ResponseWriter, r *http.Request, i *issue) {
+	var slot jira.TimeLog
+	if err := json.NewDecoder(r.Body).Decode(&slot); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	seconds, err := timeSpent(slot)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if _, err := time.Parse(StartedFormat, slot.Started); err != nil {
+		writeError(w, http.StatusBadRequest, "Worklog must have a valid start date.")
+		return
+	}
+
+	s.addWorklog(i, s.Email, slot.Started, seconds, slot.Comment)
+	writeJSON(w, http.StatusCreated, s.worklogView(i.worklogs[len(i.worklogs)-1]))
+}
+
+func (s *Server) worklog(w http.ResponseWriter, r *http.Request, i *issue, id string) {
+	var index = -1
+	for n, log := range i.worklogs {
+		if log.Id == id {
+			index = n
+		}
+	}
+	if index < 0 {
+		writeError(w, http.StatusNotFound, "Cannot find worklog with id: "+id+".")
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		writeJSON(w, http.StatusOK, s.worklogView(i.worklogs[index]))
+	case "PUT":
+		var slot jira.TimeLog
+		if err := json.NewDecoder(r.Body).Decode(&slot); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if slot.TimeSpent != "" || slot.TimeSpentSeconds != 0 {
+			seconds, err := timeSpent(slot)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			i.worklogs[index].TimeSpentSeconds = seconds
+		}
+		if slot.Started != "" {
+			i.worklogs[index].Started = slot.Started
+		}
+		if slot.Comment != nil {
+			i.worklogs[index].Comment = slot.Comment
+		}
+		s.touch(&i.worklogs[index])
+		writeJSON(w, http.StatusOK, s.worklogView(i.worklogs[index]))
+	case "DELETE":
+		deletedId, _ := strconv.ParseInt(id, 10, 64)
+		s.deleted = append(s.deleted, jira.WorklogChange{WorklogId: deletedId, UpdatedTime: s.now()})
+		i.worklogs = append(i.worklogs[:index], i.worklogs[index+1:]...)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// authenticated checks the basic credentials, or the bearer token of Server deployments.
+func (s *Server) authenticated(r *http.Request) bool {
+	if s.Deployment == jira.Server && r.Header.Get("Authorization") == "Bearer "+s.Token {
+		return true
+	}
+	email, token, ok := r.BasicAuth()
+	return ok && email == s.Email && token == s.Token
+}
+
+// user returns the user as the API shows it: by account ID on Cloud and by name on Server.
+func (s *Server) user(user jira.User) jira.User {
+	if s.HideEmails {
+		user.EmailAddress = ""
+	}
+	if s.Deployment == jira.Server {
+		user.AccountId = ""
+	} else {
+		user.Name = ""
+	}
+	return user
+}
+
+// worklogView returns the worklog as the API shows it, with a plain text comment on Server.
+func (s *Server) worklogView(log jira.Worklog) interface{} {
+	log.Author = s.user(log.Author)
+	if s.Deployment != jira.Server {
+		return log
+	}
+	return struct {
+		Id               string    `json:"id"`
+		IssueId          string    `json:"issueId"`
+		Started          string    `json:"started"`
+		TimeSpentSeconds int       `json:"timeSpentSeconds"`
+		Author           jira.User `json:"author"`
+		Comment          string    `json:"comment,omitempty"`
+		Updated          string    `json:"updated,omitempty"`
+	}{log.Id, log.IssueId, log.Started, log.TimeSpentSeconds, log.Author, log.Comment.Text(), log.Updated}
+}
+
+func (s *Server) pageSize(r *http.Request) int {
+	if maxResults, err := strconv.Atoi(r.URL.Query().Get("maxResults")); err == nil && maxResults > 0 && maxResults < s.PageSize {
+		return maxResults
+	}
+	return s.PageSize
+}
+
+// timeSpent returns the seconds of the slot, from timeSpentSeconds or else timeSpent.
+func timeSpent(slot jira.TimeLog) (int, error) {
+	if slot.TimeSpentSeconds != 0 {
+		if slot.TimeSpentSeconds < 60 {
+			return 0, fmt.Errorf("Time Spent must be at least one minute.")
+		}
+		return slot.TimeSpentSeconds, nil
+	}
+	return parseTimeSpent(slot.TimeSpent)
+}
+
+// parseTimeSpent reads Jira durations such as "1h 30m", "2d" or "45m", with 8 hour days and 5 day weeks.
+func parseTimeSpent(spent string) (int, error) {
+	var units = map[byte]int{'w': 5 * 8 * 3600, 'd': 8 * 3600, 'h': 3600, 'm': 60}
+	var total int
+	var fields = strings.Fields(spent)
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("Worklog must not be null.")
+	}
+	for _, field := range fields {
+		var unit, found = units[field[len(field)-1]]
+		var number = strings.TrimRight(field, "wdhm")
+		if !found {
+			unit, number = 60, field
+		}
+		value, err := strconv.ParseFloat(number, 64)
+		if err != nil {
+			return 0, fmt.Errorf("Worklog must not be null.")
+		}
+		total += int(value * float64(unit))
+	}
+	return total, nil
+}
+
+func millis(value string) time.Time {
+	ms, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(0, ms*int64(time.Millisecond))
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, jira.Response{ErrorMessages: []string{message}})
+}
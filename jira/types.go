@@ -0,0 +1,212 @@
+package jira
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+/**
+ * Package name: jira
+ * Project name: timesheet
+ * Created on: 18/10/2026 10:26
+ */
+
+type (
+	// TimeLog is the body of a worklog booked or updated. TimeSpentSeconds takes precedence over TimeSpent,
+	// which Jira parses itself.
+	TimeLog struct {
+		Started          string   `json:"started,omitempty"`
+		TimeSpent        string   `json:"timeSpent,omitempty"`
+		TimeSpentSeconds int      `json:"timeSpentSeconds,omitempty"`
+		Comment          *Comment `json:"comment,omitempty"`
+	}
+
+	Comment struct {
+		Version     int    `json:"version"`
+		CommentType string `json:"type"`
+		Content     []*Doc `json:"content"`
+	}
+
+	Doc struct {
+		ContentType string       `json:"type"`
+		Content     []*Paragraph `json:"content"`
+	}
+
+	// Paragraph is a node inside a block of a comment: a piece of text, a hard break, or a nested block such
+	// as the list items of a list and their paragraphs.
+	Paragraph struct {
+		Text     string       `json:"text"`
+		TextType string       `json:"type"`
+		Content  []*Paragraph `json:"content,omitempty"`
+	}
+
+	Response struct {
+		ErrorMessages []string `json:"errorMessages"`
+	}
+
+	SearchResult struct {
+		StartAt    int           `json:"startAt"`
+		MaxResults int           `json:"maxResults"`
+		Total      int           `json:"total"`
+		Issues     []SearchIssue `json:"issues"`
+	}
+
+	SearchIssue struct {
+		Id     string      `json:"id"`
+		Key    string      `json:"key"`
+		Fields IssueFields `json:"fields"`
+	}
+
+	// IssueFields are the fields Search and Issue ask for. Parent is the epic of standard issues on Jira
+	// Cloud and the issue of a sub-task on every deployment. Status is only returned by Issue.
+	IssueFields struct {
+		Summary    string      `json:"summary"`
+		Project    *Project    `json:"project,omitempty"`
+		Parent     *Parent     `json:"parent,omitempty"`
+		Components []Component `json:"components,omitempty"`
+		Labels     []string    `json:"labels,omitempty"`
+		IssueType  *IssueType  `json:"issuetype,omitempty"`
+		Status     *Status     `json:"status,omitempty"`
+	}
+
+	Project struct {
+		Key  string `json:"key"`
+		Name string `json:"name"`
+	}
+
+	Parent struct {
+		Id     string `json:"id"`
+		Key    string `json:"key"`
+		Fields struct {
+			Summary string `json:"summary"`
+		} `json:"fields"`
+	}
+
+	Component struct {
+		Name string `json:"name"`
+	}
+
+	IssueType struct {
+		Name string `json:"name"`
+	}
+
+	// Status is the workflow status of an issue. Every status belongs to one of the categories "new",
+	// "indeterminate" and "done".
+	Status struct {
+		Name           string         `json:"name"`
+		StatusCategory StatusCategory `json:"statusCategory"`
+	}
+
+	StatusCategory struct {
+		Key  string `json:"key"`
+		Name string `json:"name"`
+	}
+
+	WorkLogs struct {
+		Key        string
+		Summary    string
+		StartAt    int       `json:"startAt"`
+		MaxResults int       `json:"maxResults"`
+		Total      int       `json:"total"`
+		Worklogs   []Worklog `json:"worklogs"`
+	}
+
+	Worklog struct {
+		Id               string   `json:"id"`
+		TimeSpentSeconds int      `json:"timeSpentSeconds"`
+		IssueId          string   `json:"issueId"`
+		Started          string   `json:"started"`
+		Author           User     `json:"author"`
+		Comment          *Comment `json:"comment"`
+		Updated          string   `json:"updated,omitempty"`
+	}
+
+	// WorklogChange is an entry of the worklog change feeds: the ID of a worklog updated or deleted at
+	// UpdatedTime, in milliseconds since the epoch.
+	WorklogChange struct {
+		WorklogId   int64 `json:"worklogId"`
+		UpdatedTime int64 `json:"updatedTime"`
+	}
+
+	// WorklogChanges is a page of a worklog change feed, covering the changes from Since up to Until.
+	WorklogChanges struct {
+		Values   []WorklogChange `json:"values"`
+		Since    int64           `json:"since"`
+		Until    int64           `json:"until"`
+		LastPage bool            `json:"lastPage"`
+	}
+
+	// User is a Jira user. Cloud sites identify users by AccountId and may hide EmailAddress depending on
+	// the profile visibility settings, Server deployments identify them by Name.
+	User struct {
+		AccountId    string `json:"accountId,omitempty"`
+		Name         string `json:"name,omitempty"`
+		EmailAddress string `json:"emailAddress,omitempty"`
+		DisplayName  string `json:"displayName,omitempty"`
+	}
+)
+
+// NewComment wraps plain text into the Atlassian document format used by worklog comments.
+func NewComment(text string) *Comment {
+	var comment = Comment{}
+	var doc = Doc{}
+	var paragraph = Paragraph{}
+	paragraph.Text = text
+	paragraph.TextType = "text"
+	doc.ContentType = "paragraph"
+	doc.Content = append(doc.Content, &paragraph)
+	comment.Version = 1
+	comment.CommentType = "doc"
+	comment.Content = append(comment.Content, &doc)
+	return &comment
+}
+
+// UnmarshalJSON accepts both the Atlassian document format of Cloud sites and the plain text comments
+// of Server deployments.
+func (c *Comment) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var text string
+		if err := json.Unmarshal(data, &text); err != nil {
+			return err
+		}
+		*c = *NewComment(text)
+		return nil
+	}
+	type document Comment
+	return json.Unmarshal(data, (*document)(c))
+}
+
+// Text returns the plain text of the comment, its paragraphs joined by newlines, or an empty string when
+// there is none. Formatting is dropped.
+func (c *Comment) Text() string {
+	if c == nil {
+		return ""
+	}
+	var text strings.Builder
+	for i, block := range c.Content {
+		if i > 0 {
+			text.WriteString("\n")
+		}
+		for _, node := range block.Content {
+			node.writeText(&text)
+		}
+	}
+	return text.String()
+}
+
+// writeText writes the text of the node to text, starting a new line for each nested block.
+func (p *Paragraph) writeText(text *strings.Builder) {
+	switch {
+	case p.TextType == "hardBreak":
+		text.WriteString("\n")
+	case p.Content != nil:
+		if text.Len() > 0 && !strings.HasSuffix(text.String(), "\n") {
+			text.WriteString("\n")
+		}
+		for _, node := range p.Content {
+			node.writeText(text)
+		}
+	default:
+		text.WriteString(p.Text)
+	}
+}
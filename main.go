@@ -1,10 +1,17 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
+	"time"
+
+	"github.com/praveenprem/timesheet/jira"
 )
 
 var SIGNATURE = `
@@ -30,41 +37,93 @@ var SIGNATURE = `
 type App struct {
 	Ticket        string
 	Comment       string
+	Date          string
+	At            string
 	Started       string
 	TimeSpent     string
-	Help          bool
+	Spent         Duration
 	Encode        string
-	TimeRemaining bool
-	History       bool
-	PrintWeek     bool
-	PrintMonth    bool
-	Version       bool
+	Concurrency   int
+	Profile       string
+	Backend       string
+	EditId        string
+	DeleteId      string
+	Yes           bool
+	Estimate      string
+	NewEstimate   string
+	IncreaseBy    string
+	Command       string
+	Args          []string
+	QueueId       int
+	Round         string
+	Format        string
+	Offline       bool
+	From          string
+	To            string
+	Interval      string
+	GroupBy       string
+	Out           io.Writer
+	In            io.Reader
 	Configuration struct {
-		Auth   string
-		Domain string
+		Profile         string
+		Auth            string
+		Domain          string
+		BaseURL         string
+		Deployment      string
+		Schedule        *Schedule
+		Calendar        Calendar
+		BalanceStart    time.Time
+		OpeningBalance  int
+		FiscalYearStart time.Month
+		ClosedStatuses  []string
+		Projects        []string
+		Rounding        string
+		DurationFormat  string
+		Location        *time.Location
 	}
+	Client jira.API
+	User   *jira.User
+
+	// flags holds the flags of the command, set by Parser.
+	flags *flag.FlagSet
 }
 
 type Application interface {
-	Parser()
+	Parser() error
 	CredentialEncode()
-	GetTimeRemaining(domain string, auth string)
-	GetHistory()
-	GetWeekTimesheet(domain string, auth string)
-	GetMonthTimesheet(domain string, auth string)
+	CredentialLogin(in io.Reader, out io.Writer) error
+	GetTimeRemaining(ctx context.Context) error
+	GetHistory(ctx context.Context) error
+	GetWeekTimesheet(ctx context.Context) error
+	GetMonthTimesheet(ctx context.Context) error
+	GetBalance(ctx context.Context) error
+	GetRange(ctx context.Context) error
+	ListWorklogs(ctx context.Context) error
+	EditWorklog(ctx context.Context) error
+	DeleteWorklog(ctx context.Context) error
+	RunTimer(ctx context.Context) error
+	RunQueue() error
+	SyncQueue(ctx context.Context) error
 }
 
 var VERSION string
 
-func (app *App) upgrade() {
+// upgradeTimeout bounds the release check, so a slow or unreachable GitHub doesn't hold up the command.
+const upgradeTimeout = 3 * time.Second
+
+// upgrade checks GitHub for a newer release. It is best effort: failures are returned to the caller,
+// which is free to ignore them.
+func (app *App) upgrade(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, upgradeTimeout)
+	defer cancel()
 	var client = &http.Client{}
-	req, rErr := http.NewRequest("GET", "https://api.github.com/repos/praveenprem/timesheet/releases/latest", nil)
+	req, rErr := http.NewRequestWithContext(ctx, "GET", "https://api.github.com/repos/praveenprem/timesheet/releases/latest", nil)
 	if rErr != nil {
-		panic(rErr)
+		return rErr
 	}
 	resp, err := client.Do(req)
 	if err != nil {
-		panic(err)
+		return err
 	}
 	defer resp.Body.Close()
 	var response struct {
@@ -73,51 +132,79 @@ func (app *App) upgrade() {
 	}
 	decodeErr := json.NewDecoder(resp.Body).Decode(&response)
 	if decodeErr != nil {
-		panic(decodeErr)
+		return decodeErr
 	}
 
 	if fmt.Sprintf("v%s", VERSION) != response.Name {
-		fmt.Println("New version available! Please download the latest release from", response.URL)
+		fmt.Fprintln(os.Stderr, "New version available! Please download the latest release from", response.URL)
+	}
+	return nil
+}
+
+// out returns where reports are written, the standard output unless set otherwise.
+func (app *App) out() io.Writer {
+	if app.Out == nil {
+		return os.Stdout
+	}
+	return app.Out
+}
+
+// in returns where answers to questions are read from, the standard input unless In is set.
+func (app *App) in() io.Reader {
+	if app.In == nil {
+		return os.Stdin
+	}
+	return app.In
+}
+
+// connect creates the Jira client from the loaded configuration, unless one was given already.
+func (app *App) connect() {
+	if app.Client == nil {
+		var client = jira.NewClient(app.Configuration.Domain, app.Configuration.Auth)
+		if app.Configuration.BaseURL != "" {
+			client.BaseURL = app.Configuration.BaseURL
+		}
+		if app.Configuration.Deployment != "" {
+			client.Deployment = app.Configuration.Deployment
+		}
+		app.Client = client
 	}
 }
 
 func main() {
 	var app App
+	var ctx = context.Background()
 
 	defer func() {
 		if err := recover(); err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+			fmt.Fprintln(os.Stderr, "unexpected error:", err)
+			os.Exit(ExitFailure)
 		}
 	}()
 
-	app.Parser()
-	app.loadConf()
-	app.upgrade()
-
-	fmt.Println("This might take a moment....")
-
-	if app.TimeRemaining {
-		app.GetTimeRemaining(app.Configuration.Domain, app.Configuration.Auth)
-		os.Exit(0)
+	if err := app.run(ctx); errors.Is(err, flag.ErrHelp) {
+		os.Exit(ExitOK)
+	} else if err != nil {
+		fmt.Fprintln(os.Stderr, errorMessage(err))
+		os.Exit(exitCode(err))
 	}
+}
 
-	if app.History {
-		app.GetHistory()
-		os.Exit(0)
+// run parses the command line, prepares what the command needs and runs it.
+func (app *App) run(ctx context.Context) error {
+	if err := app.Parser(); err != nil {
+		return err
 	}
-
-	if app.PrintWeek {
-		app.GetWeekTimesheet(app.Configuration.Domain, app.Configuration.Auth)
-		os.Exit(0)
+	var command = findCommand(app.Command)
+	if command.Setup >= setupConfig {
+		if err := app.loadConf(); err != nil {
+			return err
+		}
+		app.connect()
 	}
-
-	if app.PrintMonth {
-		app.GetMonthTimesheet(app.Configuration.Domain, app.Configuration.Auth)
-		os.Exit(0)
+	if command.Setup == setupReport && !app.Offline {
+		_ = app.upgrade(ctx)
+		fmt.Fprintln(os.Stderr, "This might take a moment....")
 	}
-
-	LogTime(app.Ticket, app.TimeSpent, app.Started, app.Comment, app.Configuration.Domain, app.Configuration.Auth)
-	app.GetTimeRemaining(app.Configuration.Domain, app.Configuration.Auth)
-
+	return command.Run(app, ctx)
 }
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/praveenprem/timesheet/jira"
+)
+
+/**
+ * Package name: main
+ * Project name: timesheet
+ * Created on: 18/10/2026 10:43
+ */
+
+// Actions of the queue command.
+var queueActions = []string{"list", "edit", "drop"}
+
+type (
+	// QueuedWorklog is a booking which couldn't reach Jira, waiting to be replayed by sync.
+	QueuedWorklog struct {
+		Id        int       `json:"id"`
+		Profile   string    `json:"profile,omitempty"`
+		Ticket    string    `json:"ticket"`
+		TimeSpent string    `json:"timeSpent"`
+		Started   string    `json:"started"`
+		Comment   string    `json:"comment,omitempty"`
+		QueuedAt  time.Time `json:"queuedAt"`
+		LastError string    `json:"lastError,omitempty"`
+	}
+
+	// Journal is the offline queue, replayed in order.
+	Journal struct {
+		NextId  int             `json:"nextId"`
+		Entries []QueuedWorklog `json:"entries"`
+	}
+)
+
+func journalPath() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", &ConfigError{Msg: "unable to locate the state directory", Err: err}
+	}
+	return filepath.Join(dir, "queue.json"), nil
+}
+
+// readJournal returns the offline queue, empty when nothing was queued yet.
+func readJournal() (*Journal, error) {
+	path, err := journalPath()
+	if err != nil {
+		return nil, err
+	}
+	var journal = Journal{NextId: 1}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &journal, nil
+	}
+	if err != nil {
+		return nil, &ConfigError{Msg: "unable to read the offline queue", Err: err}
+	}
+	if err := json.Unmarshal(data, &journal); err != nil {
+		return nil, &ConfigError{Msg: fmt.Sprintf("offline queue %s is corrupt", path), Err: err}
+	}
+	return &journal, nil
+}
+
+// writeJournal saves the offline queue, replacing the file in one step.
+func writeJournal(journal *Journal) error {
+	path, err := journalPath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(journal, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, data, 0600); err != nil {
+		return &ConfigError{Msg: "unable to write the offline queue", Err: err}
+	}
+	return nil
+}
+
+func (j *Journal) find(id int) int {
+	for i, entry := range j.Entries {
+		if entry.Id == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// bookTime checks the ticket and books the worklog, keeping it in the offline queue when Jira can't be
+// reached. It tells whether the worklog reached Jira.
+func (app *App) bookTime(ctx context.Context, ticket string, spent Duration, started string, comment string) (bool, error) {
+	var transportErr *jira.TransportError
+	issue, err := app.checkTicket(ctx, ticket)
+	if err != nil && !errors.As(err, &transportErr) {
+		return false, err
+	}
+	var summary string
+	if issue != nil {
+		summary = issue.Fields.Summary
+	}
+
+	worklog, err := LogTime(ctx, app.Client, app.out(), ticket, summary, spent, started, comment)
+	if err == nil {
+		app.rememberWorklog(worklog)
+		app.recordTicket(ticket, summary)
+		return true, nil
+	}
+	if !errors.As(err, &transportErr) {
+		return false, err
+	}
+
+	journal, jErr := readJournal()
+	if jErr != nil {
+		return false, fmt.Errorf("%w (and queueing failed: %v)", err, jErr)
+	}
+	var entry = QueuedWorklog{
+		Id:        journal.NextId,
+		Profile:   app.Configuration.Profile,
+		Ticket:    ticket,
+		TimeSpent: spent.String(),
+		Started:   started,
+		Comment:   comment,
+		QueuedAt:  time.Now(),
+		LastError: transportErr.Error(),
+	}
+	journal.NextId++
+	journal.Entries = append(journal.Entries, entry)
+	if jErr := writeJournal(journal); jErr != nil {
+		return false, fmt.Errorf("%w (and queueing failed: %v)", err, jErr)
+	}
+	app.recordTicket(ticket, summary)
+
+	fmt.Fprintf(os.Stderr, "%v\n", transportErr)
+	fmt.Fprintf(app.out(), "%s to issue %s queued as #%d. run \"timesheet sync\" when Jira is reachable\n", spent, ticket, entry.Id)
+	return false, nil
+}
+
+// RunQueue executes the queue command, listing the pending bookings unless another action is given.
+func (app *App) RunQueue() error {
+	var action = "list"
+	if len(app.Args) > 0 {
+		action = app.Args[0]
+	}
+	switch action {
+	case "list":
+		return app.ListQueue()
+	case "edit":
+		return app.EditQueued()
+	case "drop":
+		return app.DropQueued()
+	default:
+		return usageErrorf("unknown queue action %q. use one of: %s", action, strings.Join(queueActions, ", "))
+	}
+}
+
+// ListQueue prints the pending bookings.
+func (app *App) ListQueue() error {
+	journal, err := readJournal()
+	if err != nil {
+		return err
+	}
+	if len(journal.Entries) == 0 {
+		fmt.Fprintln(app.out(), "The offline queue is empty")
+		return nil
+	}
+	fmt.Fprintf(app.out(), "%-4s %-10s %-15s %-29s %-8s %s\n", "#", "Profile", "Issue", "Started", "Time", "Comment")
+	for _, entry := range journal.Entries {
+		fmt.Fprintf(app.out(), "%-4d %-10s %-15s %-29s %-8s %s\n", entry.Id, entry.Profile, entry.Ticket, entry.Started, entry.TimeSpent, entry.Comment)
+		if entry.LastError != "" {
+			fmt.Fprintf(app.out(), "     last attempt: %s\n", entry.LastError)
+		}
+	}
+	return nil
+}
+
+// EditQueued changes the ticket, time spent, start date or comment of a pending booking.
+func (app *App) EditQueued() error {
+	journal, err := readJournal()
+	if err != nil {
+		return err
+	}
+	var i = journal.find(app.QueueId)
+	if i < 0 {
+		return usageErrorf("no queued worklog #%d", app.QueueId)
+	}
+
+	var entry = &journal.Entries[i]
+	if app.isFlagSet("r") {
+		entry.Ticket = app.Ticket
+	}
+	if app.TimeSpent != "" {
+		entry.TimeSpent = app.Spent.String()
+	}
+	if app.isStartSet() {
+		entry.Started = app.Started
+	}
+	if app.isFlagSet("m") {
+		entry.Comment = app.Comment
+	}
+	if err := writeJournal(journal); err != nil {
+		return err
+	}
+	fmt.Fprintf(app.out(), "Queued worklog #%d updated: %s to issue %s on %s\n", entry.Id, entry.TimeSpent, entry.Ticket, entry.Started)
+	return nil
+}
+
+// DropQueued removes a pending booking without sending it.
+func (app *App) DropQueued() error {
+	journal, err := readJournal()
+	if err != nil {
+		return err
+	}
+	var i = journal.find(app.QueueId)
+	if i < 0 {
+		return usageErrorf("no queued worklog #%d", app.QueueId)
+	}
+
+	var entry = journal.Entries[i]
+	var question = fmt.Sprintf("Drop %s to issue %s on %s?", entry.TimeSpent, entry.Ticket, entry.Started)
+	if !app.confirm(os.Stdin, app.out(), question) {
+		fmt.Fprintln(app.out(), "Aborted")
+		return nil
+	}
+	journal.Entries = append(journal.Entries[:i], journal.Entries[i+1:]...)
+	if err := writeJournal(journal); err != nil {
+		return err
+	}
+	fmt.Fprintf(app.out(), "Queued worklog #%d dropped\n", entry.Id)
+	return nil
+}
+
+// SyncQueue replays the pending bookings of the profile in the order they were queued. Bookings already
+// found in Jira, e.g. because an earlier sync was interrupted after posting, are not posted twice. The
+// sync stops at the first transport error, keeping that booking and the ones after it.
+func (app *App) SyncQueue(ctx context.Context) error {
+	journal, err := readJournal()
+	if err != nil {
+		return err
+	}
+	if len(journal.Entries) == 0 {
+		fmt.Fprintln(app.out(), "The offline queue is empty")
+		return nil
+	}
+	user, err := app.currentUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	var pending []QueuedWorklog
+	var synced, failed int
+	var stop error
+	for _, entry := range journal.Entries {
+		if stop != nil || entry.Profile != app.Configuration.Profile {
+			pending = append(pending, entry)
+			continue
+		}
+
+		spent, err := ParseDuration(entry.TimeSpent, app.schedule())
+		var booked bool
+		if err == nil {
+			booked, err = app.isBooked(ctx, user, entry, spent)
+		}
+		if err == nil && booked {
+			fmt.Fprintf(app.out(), "#%d %s to issue %s is already in Jira, skipped\n", entry.Id, entry.TimeSpent, entry.Ticket)
+			synced++
+			continue
+		}
+		if err == nil {
+			var worklog *jira.Worklog
+			if worklog, err = LogTime(ctx, app.Client, app.out(), entry.Ticket, app.knownSummary(entry.Ticket), spent, entry.Started, entry.Comment); err == nil {
+				app.rememberWorklog(worklog)
+				synced++
+				continue
+			}
+		}
+
+		entry.LastError = err.Error()
+		pending = append(pending, entry)
+		var transportErr *jira.TransportError
+		if errors.As(err, &transportErr) {
+			stop = err
+		} else {
+			fmt.Fprintf(os.Stderr, "#%d: %v\n", entry.Id, err)
+			failed++
+		}
+	}
+
+	journal.Entries = pending
+	if err := writeJournal(journal); err != nil {
+		return err
+	}
+	fmt.Fprintf(app.out(), "%d queued worklog(s) synced, %d left in the queue\n", synced, len(pending))
+	if stop != nil {
+		return stop
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d queued worklog(s) were rejected by Jira, fix them with \"timesheet queue edit\" or drop them", failed)
+	}
+	return nil
+}
+
+// isBooked tells whether the user already has a worklog on the ticket identical to the queued one.
+func (app *App) isBooked(ctx context.Context, user *jira.User, entry QueuedWorklog, spent Duration) (bool, error) {
+	started, err := time.Parse(jiraTimestampFormat, entry.Started)
+	if err != nil {
+		return false, nil
+	}
+
+	worklogs, err := app.Client.Worklogs(ctx, entry.Ticket, started.Add(-time.Minute), started.Add(time.Minute))
+	if err != nil {
+		return false, err
+	}
+	for _, log := range worklogs.Worklogs {
+		logStarted, err := time.Parse(jiraTimestampFormat, log.Started)
+		if err != nil || !logStarted.Equal(started) {
+			continue
+		}
+		if isAuthor(user, log) && log.TimeSpentSeconds == spent.Seconds() && log.Comment.Text() == entry.Comment {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/**
+ * Package name: main
+ * Project name: timesheet
+ * Created on: 18/10/2026 10:57
+ */
+
+// ReportRange is the kind of report of the range command.
+const ReportRange = "range"
+
+var (
+	// rangeIntervals lists the values accepted by -interval.
+	rangeIntervals = []string{"day", "week", "month"}
+	// namedPeriods lists the periods known by name, offered by the completion.
+	namedPeriods = []string{"today", "yesterday", "this-week", "last-week", "this-month", "last-month", "this-quarter",
+		"last-quarter", "this-year", "last-year", "this-fiscal-year", "last-fiscal-year"}
+	yearPeriod       = regexp.MustCompile(`^([0-9]{4})$`)
+	monthPeriod      = regexp.MustCompile(`^([0-9]{4})-([0-9]{2})$`)
+	quarterPeriod    = regexp.MustCompile(`^([0-9]{4})-q([1-4])$`)
+	fiscalYearPeriod = regexp.MustCompile(`^fy([0-9]{4})$`)
+)
+
+// RangeRow is the time booked on one group of -group-by in one interval of the range report. Period names the
+// interval: the day, the first day of the week or the month.
+type RangeRow struct {
+	Period  string `json:"period"`
+	Start   string `json:"start"`
+	End     string `json:"end"`
+	Group   string `json:"group"`
+	Seconds int    `json:"seconds"`
+}
+
+// GetRange reports the time booked between two dates, given by -from and -to or by a named period, summed
+// by -interval and -group-by.
+func (app *App) GetRange(ctx context.Context) error {
+	day, err := time.Parse(YmdFormat, app.getDate())
+	if err != nil {
+		return err
+	}
+	start, end, err := app.rangeDates(day)
+	if err != nil {
+		return err
+	}
+
+	entries, err := app.collectEntries(ctx, start, end, func(started string) bool {
+		return app.isDateBetween(started, start, end)
+	})
+	if err != nil {
+		return err
+	}
+
+	var report = newReport(ReportRange, start, end, entries)
+	report.DaySeconds = app.schedule().averageDay()
+	report.Interval, report.GroupBy = app.Interval, app.GroupBy
+	if report.GroupBy == "" {
+		report.GroupBy = "issue"
+	}
+	report.Rows = rangeRows(report.Entries, start, end, report.Interval, report.GroupBy, app.schedule())
+	app.addBalance(report)
+	return app.render(report)
+}
+
+// rangeDates returns the first and last day of the range report, from the period argument or -from and -to.
+// -to defaults to the day of -d.
+func (app *App) rangeDates(day time.Time) (time.Time, time.Time, error) {
+	if len(app.Args) > 0 {
+		return periodDates(app.Args[0], day, app.schedule(), app.fiscalYearStart())
+	}
+	start, err := time.Parse(YmdFormat, app.From)
+	if err != nil {
+		return start, start, usageErrorf("-from %q didn't match the YYYY-MM-DD format", app.From)
+	}
+	var end = day
+	if app.To != "" {
+		if end, err = time.Parse(YmdFormat, app.To); err != nil {
+			return start, end, usageErrorf("-to %q didn't match the YYYY-MM-DD format", app.To)
+		}
+	}
+	if end.Before(start) {
+		return start, end, usageErrorf("-to %s is before -from %s", end.Format(YmdFormat), start.Format(YmdFormat))
+	}
+	return start, end, nil
+}
+
+// fiscalYearStart returns the month the fiscal year of the profile starts in, January by default.
+func (app *App) fiscalYearStart() time.Month {
+	if app.Configuration.FiscalYearStart == 0 {
+		return time.January
+	}
+	return app.Configuration.FiscalYearStart
+}
+
+// periodDates returns the first and last day of a named period relative to today: today, yesterday,
+// this-week, last-week, this-month, last-month, this-quarter, last-quarter, this-year, last-year,
+// this-fiscal-year and last-fiscal-year, or a year (2026), month (2026-07), quarter (2026-Q3) or fiscal year
+// (FY2026, the one ending in 2026).
+func periodDates(name string, today time.Time, schedule Schedule, fiscalStart time.Month) (time.Time, time.Time, error) {
+	var period = strings.ToLower(name)
+	var year, month, _ = today.Date()
+	var quarter = (int(month)-1)/3 + 1
+	var fiscalYear = year
+	if fiscalStart > time.January && month >= fiscalStart {
+		fiscalYear++
+	}
+
+	switch period {
+	case "today":
+		return today, today, nil
+	case "yesterday":
+		var day = today.AddDate(0, 0, -1)
+		return day, day, nil
+	case "this-week":
+		var start, end = schedule.weekOf(today)
+		return start, end, nil
+	case "last-week":
+		var start, end = schedule.weekOf(today.AddDate(0, 0, -7))
+		return start, end, nil
+	case "this-month":
+		return monthDates(year, month)
+	case "last-month":
+		return monthDates(year, month-1)
+	case "this-quarter":
+		return quarterDates(year, quarter)
+	case "last-quarter":
+		return quarterDates(year, quarter-1)
+	case "this-year":
+		return monthsDates(year, time.January, 12)
+	case "last-year":
+		return monthsDates(year-1, time.January, 12)
+	case "this-fiscal-year":
+		return fiscalYearDates(fiscalYear, fiscalStart)
+	case "last-fiscal-year":
+		return fiscalYearDates(fiscalYear-1, fiscalStart)
+	}
+
+	if match := yearPeriod.FindStringSubmatch(period); match != nil {
+		return monthsDates(atoi(match[1]), time.January, 12)
+	}
+	if match := monthPeriod.FindStringSubmatch(period); match != nil {
+		if m := atoi(match[2]); m >= 1 && m <= 12 {
+			return monthDates(atoi(match[1]), time.Month(m))
+		}
+	}
+	if match := quarterPeriod.FindStringSubmatch(period); match != nil {
+		return quarterDates(atoi(match[1]), atoi(match[2]))
+	}
+	if match := fiscalYearPeriod.FindStringSubmatch(period); match != nil {
+		return fiscalYearDates(atoi(match[1]), fiscalStart)
+	}
+	return today, today, usageErrorf("unknown period %q. e.g. last-week, this-quarter, 2026-Q3 or FY2026", name)
+}
+
+// monthsDates returns the first and last day of count months starting with month. Months out of range roll
+// over into the year before or after.
+func monthsDates(year int, month time.Month, count int) (time.Time, time.Time, error) {
+	var start = time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, count, -1), nil
+}
+
+func monthDates(year int, month time.Month) (time.Time, time.Time, error) {
+	return monthsDates(year, month, 1)
+}
+
+// quarterDates returns the first and last day of a quarter. Quarter 0 is the last quarter of the year before.
+func quarterDates(year int, quarter int) (time.Time, time.Time, error) {
+	return monthsDates(year, time.Month((quarter-1)*3+1), 3)
+}
+
+// fiscalYearDates returns the first and last day of the fiscal year ending in year.
+func fiscalYearDates(year int, start time.Month) (time.Time, time.Time, error) {
+	if start > time.January {
+		year--
+	}
+	return monthsDates(year, start, 12)
+}
+
+// atoi converts digits already matched by a pattern.
+func atoi(digits string) int {
+	value, _ := strconv.Atoi(digits)
+	return value
+}
+
+// rangeRows sums the entries by interval from start to end and by group within each interval. Intervals are
+// cut at the start and end of the range and groups are sorted by name.
+func rangeRows(entries []ReportEntry, start time.Time, end time.Time, interval string, groupBy string, schedule Schedule) []RangeRow {
+	var rows = []RangeRow{}
+	for from := start; !from.After(end); {
+		var period string
+		var to time.Time
+		switch interval {
+		case "day":
+			period, to = from.Format(YmdFormat), from
+		case "week":
+			var weekStart, weekEnd = schedule.weekOf(from)
+			period, to = weekStart.Format(YmdFormat), weekEnd
+		default:
+			period, to = from.Format("2006-01"), time.Date(from.Year(), from.Month()+1, 0, 0, 0, 0, 0, time.UTC)
+		}
+		if to.After(end) {
+			to = end
+		}
+
+		var first, last = from.Format(YmdFormat), to.Format(YmdFormat)
+		var seconds = make(map[string]int)
+		for _, entry := range entries {
+			if entry.Date >= first && entry.Date <= last {
+				seconds[groupOf(entry, groupBy)] += entry.Seconds
+			}
+		}
+		var groups []string
+		for group := range seconds {
+			groups = append(groups, group)
+		}
+		sort.Strings(groups)
+		for _, group := range groups {
+			rows = append(rows, RangeRow{Period: period, Start: first, End: last, Group: group, Seconds: seconds[group]})
+		}
+		from = to.AddDate(0, 0, 1)
+	}
+	return rows
+}
+
+// printRange writes the rows of the range report as a table, each interval followed by its total.
+func printRange(w io.Writer, report *Report) {
+	var group = strings.ToUpper(report.GroupBy[:1]) + report.GroupBy[1:]
+	fmt.Fprintf(w, "Timesheet %s to %s by %s and %s:\n", report.Start, report.End, report.Interval, report.GroupBy)
+	fmt.Fprintf(w, "%-12s %-16s %10s\n", "Period", group, "Hours")
+	for i, row := range report.Rows {
+		var period = row.Period
+		if i > 0 && report.Rows[i-1].Period == row.Period {
+			period = ""
+		}
+		fmt.Fprintf(w, "%-12s %-16s %10s\n", period, row.Group, Duration(row.Seconds).Text(report.durations, "%.2f"))
+		if i == len(report.Rows)-1 || report.Rows[i+1].Period != row.Period {
+			var total int
+			for _, other := range report.Rows {
+				if other.Period == row.Period {
+					total += other.Seconds
+				}
+			}
+			fmt.Fprintf(w, "%-12s %-16s %10s\n", "", "Total", Duration(total).Text(report.durations, "%.2f"))
+		}
+	}
+	fmt.Fprintf(w, "%-29s %10s\n", "Total", Duration(report.Total).Text(report.durations, "%.2f"))
+	fmt.Fprintf(w, "%-29s %10s\n", "Expected", Duration(report.Expected).Text(report.durations, "%.2f"))
+}
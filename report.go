@@ -0,0 +1,352 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/**
+ * Package name: main
+ * Project name: timesheet
+ * Created on: 18/10/2026 10:33
+ */
+
+// Report kinds, one per report command.
+const (
+	ReportRemaining = "remaining"
+	ReportDay       = "day"
+	ReportWeek      = "week"
+	ReportMonth     = "month"
+)
+
+// reportFormats lists the values accepted by -format.
+var reportFormats = []string{"table", "json", "csv", "tsv"}
+
+// reportGroups lists the values accepted by -group-by.
+var reportGroups = []string{"issue", "project", "epic", "component", "label", "type"}
+
+type (
+	// ReportEntry is a single worklog of the user.
+	ReportEntry struct {
+		Issue       string   `json:"issue"`
+		Summary     string   `json:"summary"`
+		Project     string   `json:"project,omitempty"`
+		Epic        string   `json:"epic,omitempty"`
+		EpicSummary string   `json:"epic_summary,omitempty"`
+		Components  []string `json:"components,omitempty"`
+		Labels      []string `json:"labels,omitempty"`
+		Type        string   `json:"issue_type,omitempty"`
+		Date        string   `json:"date"`
+		Started     string   `json:"started"`
+		Seconds     int      `json:"seconds"`
+		Comment     string   `json:"comment"`
+		Author      string   `json:"author"`
+	}
+
+	// Report holds the worklogs of the user between Start and End, built before it is rendered.
+	Report struct {
+		Kind       string        `json:"report"`
+		Start      string        `json:"start"`
+		End        string        `json:"end"`
+		Total      int           `json:"total_seconds"`
+		DaySeconds int           `json:"day_seconds"`
+		Expected   int           `json:"expected_seconds"`
+		Remaining  int           `json:"remaining_seconds"`
+		Days       []DayBalance  `json:"days"`
+		Weeks      []WeekBalance `json:"weeks,omitempty"`
+		Balance    int           `json:"balance_seconds,omitempty"`
+		ToBook     int           `json:"to_book_seconds,omitempty"`
+		BookBy     string        `json:"book_by,omitempty"`
+		Interval   string        `json:"interval,omitempty"`
+		GroupBy    string        `json:"group_by,omitempty"`
+		Rows       []RangeRow    `json:"rows,omitempty"`
+		Entries    []ReportEntry `json:"entries"`
+
+		// schedule lays out the days of the week and month tables and durations is the duration_format they
+		// write times in.
+		schedule  Schedule
+		durations string
+	}
+
+	// DayBalance compares the time booked on a day with the time expected by the schedule, less holidays and
+	// leave.
+	DayBalance struct {
+		Date       string `json:"date"`
+		Booked     int    `json:"booked_seconds"`
+		Expected   int    `json:"expected_seconds"`
+		Difference int    `json:"difference_seconds"`
+		Absence    string `json:"absence,omitempty"`
+	}
+
+	// Formatter renders a report.
+	Formatter interface {
+		Format(w io.Writer, report *Report) error
+	}
+
+	tableFormatter struct{}
+
+	jsonFormatter struct{}
+
+	delimitedFormatter struct {
+		comma rune
+	}
+)
+
+// newFormatter returns the formatter for the -format value.
+func newFormatter(format string) (Formatter, error) {
+	switch format {
+	case "", "table":
+		return tableFormatter{}, nil
+	case "json":
+		return jsonFormatter{}, nil
+	case "csv":
+		return delimitedFormatter{comma: ','}, nil
+	case "tsv":
+		return delimitedFormatter{comma: '\t'}, nil
+	default:
+		return nil, usageErrorf("unknown format %q", format)
+	}
+}
+
+func newReport(kind string, start time.Time, end time.Time, entries []ReportEntry) *Report {
+	var report = Report{
+		Kind:    kind,
+		Start:   start.Format(YmdFormat),
+		End:     end.Format(YmdFormat),
+		Entries: entries,
+	}
+	if report.Entries == nil {
+		report.Entries = []ReportEntry{}
+	}
+	sort.SliceStable(report.Entries, func(i, j int) bool {
+		return report.Entries[i].Started < report.Entries[j].Started
+	})
+	for _, entry := range report.Entries {
+		report.Total += entry.Seconds
+	}
+	return &report
+}
+
+func (tableFormatter) Format(w io.Writer, report *Report) error {
+	switch report.Kind {
+	case ReportRemaining:
+		if report.Remaining < 0 {
+			fmt.Fprintf(w, "oops... Looks like you've booked %s more that what you supposed to!\n",
+				Duration(-report.Remaining).Text(report.durations, "%.2f hours"))
+		} else {
+			fmt.Fprintf(w, "You've %s ramaining!\n", Duration(report.Remaining).Text(report.durations, "%.2f hours"))
+		}
+	case ReportDay:
+		fmt.Fprintf(w, "Timesheet history: (%s):\n", report.Start)
+		for _, entry := range report.Entries {
+			fmt.Fprintf(w, "\t%s:\n\t\t%s: %s\n\t\t%s: %s\n\t\t%s: %s\n\t\t%s: %s\n\n",
+				entry.Issue,
+				"Summary", entry.Summary,
+				"Author", entry.Author,
+				"Comment", entry.Comment,
+				"Time spent", Duration(entry.Seconds).Text(report.durations, "%.2fh"),
+			)
+		}
+		fmt.Fprintln(w, fmt.Sprintf("Total %s", Duration(report.Total).Text(report.durations, "%.1fh")))
+	case ReportWeek:
+		var weekLog = weekLogOf(report.Entries, report.GroupBy)
+		weekLog.Columns = report.schedule.columns(report.Entries)
+		weekLog.Subtotals = report.GroupBy != ""
+		if weekLog.Subtotals {
+			weekLog.Label = strings.ToUpper(report.GroupBy[:1]) + report.GroupBy[1:]
+		}
+		weekLog.Balance = balanceByWeekday(report.Days, report.Start, report.End)
+		weekLog.Durations = report.durations
+		weekLog.print(w)
+	case ReportMonth:
+		start, err := time.Parse(YmdFormat, report.Start)
+		if err != nil {
+			return err
+		}
+		var month = Month{Total: report.Total, Expected: report.Expected, SecondsInDay: report.DaySeconds,
+			Columns: report.schedule.columns(report.Entries), Durations: report.durations}
+		var weeks = report.schedule.weeksOfMonth(start)
+		var numbers []int
+		for wNum := range weeks {
+			numbers = append(numbers, wNum)
+		}
+		sort.Slice(numbers, func(i, j int) bool { return weeks[numbers[i]][0].Before(weeks[numbers[j]][0]) })
+		for _, wNum := range numbers {
+			var dates = weeks[wNum]
+			var first, last = dates[0].Format(YmdFormat), dates[len(dates)-1].Format(YmdFormat)
+			var entries []ReportEntry
+			for _, entry := range report.Entries {
+				if entry.Date >= first && entry.Date <= last {
+					entries = append(entries, entry)
+				}
+			}
+			var weekLog = weekLogOf(entries, "")
+			weekLog.Columns = month.Columns
+			var week = NumberWeek{Week: weekLog.sort(), Number: wNum, Balance: balanceByWeekday(report.Days, first, last)}
+			if report.GroupBy != "" {
+				week.Groups = make(map[string]map[string]int)
+				for _, entry := range entries {
+					var group = groupOf(entry, report.GroupBy)
+					if week.Groups[group] == nil {
+						week.Groups[group] = make(map[string]int)
+					}
+					week.Groups[group][getDateOfWeek(entry.Date)] += entry.Seconds
+				}
+			}
+			month.Weeks = append(month.Weeks, week)
+		}
+		month.print(w)
+	case ReportBalance:
+		printWeekBalances(w, report)
+	case ReportRange:
+		printRange(w, report)
+	default:
+		return fmt.Errorf("no table layout for %s reports", report.Kind)
+	}
+	return nil
+}
+
+func (jsonFormatter) Format(w io.Writer, report *Report) error {
+	var encoder = json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// Format writes one row per worklog. The remaining report has no worklogs to list and writes a single
+// row with the booked, expected and remaining seconds of the day instead, while the balance report writes
+// its weeks and the range report, and the week and month reports given -group-by, their rows.
+func (f delimitedFormatter) Format(w io.Writer, report *Report) error {
+	var writer = csv.NewWriter(w)
+	writer.Comma = f.comma
+
+	if report.Kind == ReportBalance {
+		_ = writer.Write([]string{"week_start", "week_end", "booked_seconds", "expected_seconds", "delta_seconds", "balance_seconds"})
+		for _, week := range report.Weeks {
+			_ = writer.Write([]string{week.Start, week.End, strconv.Itoa(week.Booked), strconv.Itoa(week.Expected),
+				strconv.Itoa(week.Delta), strconv.Itoa(week.Balance)})
+		}
+	} else if report.Kind == ReportRange || report.GroupBy != "" {
+		_ = writer.Write([]string{"period", "start", "end", report.GroupBy, "seconds"})
+		for _, row := range report.Rows {
+			_ = writer.Write([]string{row.Period, row.Start, row.End, row.Group, strconv.Itoa(row.Seconds)})
+		}
+	} else if report.Kind == ReportRemaining {
+		_ = writer.Write([]string{"date", "booked_seconds", "expected_seconds", "remaining_seconds"})
+		_ = writer.Write([]string{report.Start, strconv.Itoa(report.Total), strconv.Itoa(report.Expected),
+			strconv.Itoa(report.Remaining)})
+	} else {
+		_ = writer.Write([]string{"issue", "summary", "date", "seconds", "comment"})
+		for _, entry := range report.Entries {
+			_ = writer.Write([]string{entry.Issue, entry.Summary, entry.Date, strconv.Itoa(entry.Seconds), entry.Comment})
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// balanceByWeekday returns the balance of the days from first to last by the name of their day of the week.
+func balanceByWeekday(days []DayBalance, first string, last string) map[string]DayBalance {
+	var balance = make(map[string]DayBalance)
+	for _, day := range days {
+		if day.Date >= first && day.Date <= last {
+			balance[getDateOfWeek(day.Date)] = day
+		}
+	}
+	return balance
+}
+
+// groupOf returns the group of the entry for -group-by. Epics are named by their key and summary. An entry
+// with several components or labels counts once, towards all of them joined, and an entry without any towards
+// "(none)".
+func groupOf(entry ReportEntry, groupBy string) string {
+	var names []string
+	switch groupBy {
+	case "project":
+		names = []string{entry.Project}
+		if entry.Project == "" {
+			names = []string{strings.SplitN(entry.Issue, "-", 2)[0]}
+		}
+	case "epic":
+		names = []string{strings.TrimSpace(entry.Epic + " " + entry.EpicSummary)}
+	case "component":
+		names = append(names, entry.Components...)
+	case "label":
+		names = append(names, entry.Labels...)
+	case "type":
+		names = []string{entry.Type}
+	default:
+		return entry.Issue
+	}
+	sort.Strings(names)
+	if group := strings.Join(names, ", "); group != "" {
+		return group
+	}
+	return "(none)"
+}
+
+// weekLogOf groups the entries by issue, or by the group of -group-by when given, in the order the groups
+// first appear.
+func weekLogOf(entries []ReportEntry, groupBy string) WeekLog {
+	var weekLog WeekLog
+	var index = make(map[string]int)
+	for _, entry := range entries {
+		var key = groupOf(entry, groupBy)
+		i, found := index[key]
+		if !found {
+			i = len(weekLog.Issues)
+			index[key] = i
+			weekLog.Issues = append(weekLog.Issues, Issue{Key: key})
+		}
+		weekLog.Issues[i].Logs = append(weekLog.Issues[i].Logs, DayLog{
+			WeekDay:   getDateOfWeek(entry.Date),
+			TimeSpent: entry.Seconds,
+		})
+		weekLog.Total += entry.Seconds
+	}
+	return weekLog
+}
+
+// addBalance works out the time expected on each day of the report and compares it with the time booked.
+func (app *App) addBalance(report *Report) {
+	start, sErr := time.Parse(YmdFormat, report.Start)
+	end, eErr := time.Parse(YmdFormat, report.End)
+	if sErr != nil || eErr != nil {
+		return
+	}
+
+	var booked = make(map[string]int)
+	for _, entry := range report.Entries {
+		booked[entry.Date] += entry.Seconds
+	}
+	report.Days = []DayBalance{}
+	report.Expected = 0
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		var expected, absence = app.expectedOn(day)
+		var date = day.Format(YmdFormat)
+		report.Days = append(report.Days, DayBalance{
+			Date:       date,
+			Booked:     booked[date],
+			Expected:   expected,
+			Difference: booked[date] - expected,
+			Absence:    absence,
+		})
+		report.Expected += expected
+	}
+}
+
+// render writes the report in the format chosen by -format.
+func (app *App) render(report *Report) error {
+	formatter, err := newFormatter(app.Format)
+	if err != nil {
+		return err
+	}
+	report.schedule = app.schedule()
+	report.durations = app.Configuration.DurationFormat
+	return formatter.Format(app.out(), report)
+}
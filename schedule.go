@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+/**
+ * Package name: main
+ * Project name: timesheet
+ * Created on: 18/10/2026 10:48
+ */
+
+// Schedule is the working week of the user: the seconds expected on each day of the week, zero on days off,
+// and the day weeks start on.
+type Schedule struct {
+	Seconds   [7]int
+	WeekStart time.Weekday
+	// Day is the length of a working day in seconds, what a day given as a duration counts for.
+	Day int
+}
+
+// defaultDailyHours is the length of a working day when the profile doesn't set one.
+const defaultDailyHours = 8
+
+// defaultSchedule is 8 hours from Monday to Friday.
+func defaultSchedule() Schedule {
+	var schedule = Schedule{WeekStart: time.Monday, Day: defaultDailyHours * 3600}
+	for day := time.Monday; day <= time.Friday; day++ {
+		schedule.Seconds[day] = defaultDailyHours * 3600
+	}
+	return schedule
+}
+
+// newSchedule builds the schedule of a profile. Working days are workingDays when given, the days with hours
+// otherwise, and Monday to Friday when neither is given. They last hours[day] when set and dailyHours, or 8
+// hours, otherwise.
+func newSchedule(dailyHours float64, workingDays []string, hours map[string]float64, weekStart string) (Schedule, error) {
+	var schedule = Schedule{WeekStart: time.Monday}
+	if dailyHours <= 0 {
+		dailyHours = defaultDailyHours
+	}
+	schedule.Day = int(dailyHours * 3600)
+
+	var working [7]bool
+	switch {
+	case len(workingDays) > 0:
+		for _, name := range workingDays {
+			day, err := parseWeekday(name)
+			if err != nil {
+				return schedule, err
+			}
+			working[day] = true
+		}
+	case len(hours) > 0:
+		for name, h := range hours {
+			day, err := parseWeekday(name)
+			if err != nil {
+				return schedule, err
+			}
+			working[day] = h > 0
+		}
+	default:
+		for day := time.Monday; day <= time.Friday; day++ {
+			working[day] = true
+		}
+	}
+
+	var perDay [7]float64
+	for day := range perDay {
+		perDay[day] = dailyHours
+	}
+	for name, h := range hours {
+		day, err := parseWeekday(name)
+		if err != nil {
+			return schedule, err
+		}
+		if h < 0 || h > 24 {
+			return schedule, fmt.Errorf("%s has %g hours, expected 0 to 24", name, h)
+		}
+		perDay[day] = h
+	}
+	for day := range working {
+		if working[day] {
+			schedule.Seconds[day] = int(perDay[day] * 3600)
+		}
+	}
+
+	if weekStart != "" {
+		day, err := parseWeekday(weekStart)
+		if err != nil {
+			return schedule, err
+		}
+		schedule.WeekStart = day
+	}
+	return schedule, nil
+}
+
+// parseWeekday reads the English name of a day of the week, in full or as its first three letters.
+func parseWeekday(name string) (time.Weekday, error) {
+	var lower = strings.ToLower(strings.TrimSpace(name))
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		var full = strings.ToLower(day.String())
+		if lower == full || (len(lower) == 3 && strings.HasPrefix(full, lower)) {
+			return day, nil
+		}
+	}
+	return time.Sunday, fmt.Errorf("unknown day of the week %q", name)
+}
+
+// schedule returns the schedule of the profile, the default one when there is no configuration.
+func (app *App) schedule() Schedule {
+	if app.Configuration.Schedule == nil {
+		return defaultSchedule()
+	}
+	return *app.Configuration.Schedule
+}
+
+// secondsOn returns the seconds expected on the date.
+func (s Schedule) secondsOn(date time.Time) int {
+	return s.Seconds[date.Weekday()]
+}
+
+// averageDay returns the average length of a working day, used to express hours as days.
+func (s Schedule) averageDay() int {
+	var total, days int
+	for _, seconds := range s.Seconds {
+		if seconds > 0 {
+			total += seconds
+			days++
+		}
+	}
+	if days == 0 {
+		return defaultDailyHours * 3600
+	}
+	return total / days
+}
+
+// dayLength returns the seconds a day given as a duration counts for: the daily hours of the profile.
+func (s Schedule) dayLength() int {
+	if s.Day == 0 {
+		return defaultDailyHours * 3600
+	}
+	return s.Day
+}
+
+// weekLength returns the seconds a week given as a duration counts for: a day for each working day, or five
+// when the schedule has none.
+func (s Schedule) weekLength() int {
+	var days int
+	for _, seconds := range s.Seconds {
+		if seconds > 0 {
+			days++
+		}
+	}
+	if days == 0 {
+		days = 5
+	}
+	return days * s.dayLength()
+}
+
+// weekOf returns the first and last day of the week the date is in.
+func (s Schedule) weekOf(date time.Time) (time.Time, time.Time) {
+	var offset = (int(date.Weekday()) - int(s.WeekStart) + 7) % 7
+	var start = date.AddDate(0, 0, -offset)
+	return start, start.AddDate(0, 0, 6)
+}
+
+// weekNumber returns the ISO number of the week most days of the date's week fall in, with weeks starting
+// on the schedule's day.
+func (s Schedule) weekNumber(date time.Time) int {
+	var start, _ = s.weekOf(date)
+	var toMonday = (int(time.Monday) - int(s.WeekStart) + 7) % 7
+	if toMonday > 3 {
+		toMonday -= 7
+	}
+	_, number := start.AddDate(0, 0, toMonday).ISOWeek()
+	return number
+}
+
+// weeksOfMonth groups the days of the month of date by their week number.
+func (s Schedule) weeksOfMonth(date time.Time) map[int][]time.Time {
+	var weekNumbers = make(map[int][]time.Time)
+	var end = time.Date(date.Year(), date.Month()+1, 0, 0, 0, 0, 0, date.Location())
+	for i := 1; i <= end.Day(); i++ {
+		tmpDate := time.Date(date.Year(), date.Month(), i, 0, 0, 0, 0, date.Location())
+		var wNumber = s.weekNumber(tmpDate)
+		weekNumbers[wNumber] = append(weekNumbers[wNumber], tmpDate)
+	}
+	return weekNumbers
+}
+
+// columns returns the names of the days of the week in order from the week start, leaving out days off
+// without any entries.
+func (s Schedule) columns(entries []ReportEntry) []string {
+	var booked = make(map[string]bool)
+	for _, entry := range entries {
+		booked[getDateOfWeek(entry.Date)] = true
+	}
+	var days []string
+	for i := 0; i < 7; i++ {
+		var day = time.Weekday((int(s.WeekStart) + i) % 7)
+		if s.Seconds[day] > 0 || booked[day.String()] {
+			days = append(days, day.String())
+		}
+	}
+	return days
+}
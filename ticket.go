@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/praveenprem/timesheet/jira"
+)
+
+/**
+ * Package name: main
+ * Project name: timesheet
+ * Created on: 18/10/2026 11:10
+ */
+
+const (
+	// suggestionDistance is the number of typos a recent ticket may be away from a key Jira doesn't know to be
+	// suggested instead.
+	suggestionDistance = 2
+	// suggestionCount is the number of tickets suggested at most.
+	suggestionCount = 3
+)
+
+// checkTicket looks the ticket up before time is booked on it, refusing issues in a closed status or outside
+// the projects of the profile. A ticket Jira doesn't know is reported with the recent tickets closest to it.
+func (app *App) checkTicket(ctx context.Context, key string) (*jira.SearchIssue, error) {
+	issue, err := app.Client.Issue(ctx, key)
+	var notFound *jira.NotFoundError
+	if errors.As(err, &notFound) {
+		var msg = fmt.Sprintf("issue %s doesn't exist or you don't have permission to see it", key)
+		if suggestions := suggestTickets(key, recentTickets(app.Configuration.Profile)); len(suggestions) > 0 {
+			msg += fmt.Sprintf(". did you mean %s?", strings.Join(suggestions, ", "))
+		}
+		return nil, &TicketError{Msg: msg, Err: err}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("looking up %s: %w", key, err)
+	}
+
+	if status := issue.Fields.Status; status != nil && app.isClosed(status) {
+		return issue, &TicketError{Msg: fmt.Sprintf("issue %s %q is %s, time can't be booked on closed issues",
+			key, issue.Fields.Summary, status.Name)}
+	}
+	if project := issue.Fields.Project; project != nil && !app.isAllowedProject(project.Key) {
+		return issue, &TicketError{Msg: fmt.Sprintf("issue %s %q is in project %s, which isn't one of the projects of the profile: %s",
+			key, issue.Fields.Summary, project.Key, strings.Join(app.Configuration.Projects, ", "))}
+	}
+	return issue, nil
+}
+
+// isClosed tells whether the status is one of the closed statuses of the profile, or by default in Jira's
+// Done category.
+func (app *App) isClosed(status *jira.Status) bool {
+	if app.Configuration.ClosedStatuses == nil {
+		return status.StatusCategory.Key == "done"
+	}
+	for _, closed := range app.Configuration.ClosedStatuses {
+		if strings.EqualFold(closed, status.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// isAllowedProject tells whether time can be booked on the project, any project when the profile lists none.
+func (app *App) isAllowedProject(key string) bool {
+	if len(app.Configuration.Projects) == 0 {
+		return true
+	}
+	for _, project := range app.Configuration.Projects {
+		if strings.EqualFold(project, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// suggestTickets returns the recent tickets at most suggestionDistance typos away from the key, the closest
+// first, with their summary.
+func suggestTickets(key string, recent []RecentTicket) []string {
+	type candidate struct {
+		ticket   RecentTicket
+		distance int
+	}
+	var candidates []candidate
+	for _, ticket := range recent {
+		if distance := editDistance(strings.ToUpper(key), strings.ToUpper(ticket.Key)); distance <= suggestionDistance {
+			candidates = append(candidates, candidate{ticket, distance})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].distance < candidates[j].distance
+	})
+
+	var suggestions []string
+	for _, candidate := range candidates {
+		if len(suggestions) == suggestionCount {
+			break
+		}
+		if candidate.ticket.Summary != "" {
+			suggestions = append(suggestions, fmt.Sprintf("%s (%s)", candidate.ticket.Key, candidate.ticket.Summary))
+		} else {
+			suggestions = append(suggestions, candidate.ticket.Key)
+		}
+	}
+	return suggestions
+}
+
+// editDistance counts the characters to insert, delete, replace or swap with their neighbour to turn a into b.
+func editDistance(a string, b string) int {
+	var rows = make([][]int, len(a)+1)
+	for i := range rows {
+		rows[i] = make([]int, len(b)+1)
+		rows[i][0] = i
+	}
+	for j := range rows[0] {
+		rows[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			var cost = 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			rows[i][j] = min(rows[i-1][j]+1, rows[i][j-1]+1, rows[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				rows[i][j] = min(rows[i][j], rows[i-2][j-2]+1)
+			}
+		}
+	}
+	return rows[len(a)][len(b)]
+}
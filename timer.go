@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/**
+ * Package name: main
+ * Project name: timesheet
+ * Created on: 18/10/2026 10:32
+ */
+
+type (
+	// TimerState is the running timer, persisted between invocations.
+	TimerState struct {
+		Ticket  string    `json:"ticket"`
+		Comment string    `json:"comment,omitempty"`
+		Started time.Time `json:"started"`
+		Profile string    `json:"profile,omitempty"`
+	}
+
+	// RoundingRule rounds the elapsed time of a timer to a multiple of Minutes. Mode is one of "up",
+	// "down", "nearest" or "off".
+	RoundingRule struct {
+		Mode    string
+		Minutes int
+	}
+)
+
+// stateDir returns $XDG_STATE_HOME/timesheet, falling back to ~/.local/state/timesheet.
+func stateDir() (string, error) {
+	var base = os.Getenv("XDG_STATE_HOME")
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		base = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(base, "timesheet"), nil
+}
+
+// writeFileAtomic writes the file through a temporary file renamed over it, so readers never see it half
+// written. Missing directories are created readable by the user only.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	var tmp = path + ".tmp"
+	if err := os.WriteFile(tmp, data, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func timerPath() (string, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return "", &ConfigError{Msg: "unable to locate the state directory", Err: err}
+	}
+	return filepath.Join(dir, "timer.json"), nil
+}
+
+// readTimer returns the running timer, or nil when there is none.
+func readTimer() (*TimerState, error) {
+	path, err := timerPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, &ConfigError{Msg: "unable to read the timer state", Err: err}
+	}
+	var state TimerState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, &ConfigError{Msg: fmt.Sprintf("timer state %s is corrupt, remove it to reset the timer", path), Err: err}
+	}
+	return &state, nil
+}
+
+// writeTimer saves the running timer, replacing the state file in one step so a crash can't leave it
+// half written. A nil state removes the file.
+func writeTimer(state *TimerState) error {
+	path, err := timerPath()
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return &ConfigError{Msg: "unable to clear the timer state", Err: err}
+		}
+		return nil
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, data, 0600); err != nil {
+		return &ConfigError{Msg: "unable to write the timer state", Err: err}
+	}
+	return nil
+}
+
+// parseRoundingRule reads rules such as "nearest:15", "up:30", "down:5" or "off".
+func parseRoundingRule(rule string) (RoundingRule, error) {
+	if rule == "" || rule == "off" {
+		return RoundingRule{Mode: "off"}, nil
+	}
+	var parts = strings.SplitN(rule, ":", 2)
+	if len(parts) != 2 {
+		return RoundingRule{}, fmt.Errorf("invalid rounding rule %q. expected mode:minutes, e.g. nearest:15", rule)
+	}
+	minutes, err := strconv.Atoi(parts[1])
+	if err != nil || minutes < 1 {
+		return RoundingRule{}, fmt.Errorf("invalid rounding rule %q. minutes must be a positive number", rule)
+	}
+	switch parts[0] {
+	case "up", "down", "nearest":
+		return RoundingRule{Mode: parts[0], Minutes: minutes}, nil
+	default:
+		return RoundingRule{}, fmt.Errorf("invalid rounding rule %q. mode must be up, down or nearest", rule)
+	}
+}
+
+// apply rounds the elapsed time to whole minutes following the rule.
+func (r RoundingRule) apply(elapsed time.Duration) time.Duration {
+	var minutes = elapsed.Minutes()
+	if r.Mode == "off" || r.Minutes < 1 {
+		return time.Duration(math.Round(minutes)) * time.Minute
+	}
+	var steps = minutes / float64(r.Minutes)
+	switch r.Mode {
+	case "up":
+		steps = math.Ceil(steps)
+	case "down":
+		steps = math.Floor(steps)
+	default:
+		steps = math.Round(steps)
+	}
+	return time.Duration(steps) * time.Duration(r.Minutes) * time.Minute
+}
+
+// jiraTimestampFormat is the layout of worklog start times in Jira requests and responses.
+const jiraTimestampFormat = "2006-01-02T15:04:05.000-0700"
+
+// jiraTimestamp formats a time the way Jira expects worklog start times.
+func jiraTimestamp(t time.Time) string {
+	return t.Format(jiraTimestampFormat)
+}
+
+// RunTimer executes the timer command given as the first argument.
+func (app *App) RunTimer(ctx context.Context) error {
+	switch app.Command {
+	case "start":
+		return app.StartTimer()
+	case "stop":
+		return app.StopTimer(ctx)
+	case "status":
+		return app.TimerStatus()
+	case "switch":
+		if err := app.StopTimer(ctx); err != nil {
+			return err
+		}
+		return app.StartTimer()
+	default:
+		return usageErrorf("unknown command %q", app.Command)
+	}
+}
+
+// StartTimer starts timing work on the ticket.
+func (app *App) StartTimer() error {
+	running, err := readTimer()
+	if err != nil {
+		return err
+	}
+	if running != nil {
+		return usageErrorf("a timer is already running for %s since %s. use stop or switch",
+			running.Ticket, running.Started.Format("15:04"))
+	}
+
+	var state = &TimerState{
+		Ticket:  app.Ticket,
+		Comment: app.Comment,
+		Started: time.Now(),
+		Profile: app.Profile,
+	}
+	if err := writeTimer(state); err != nil {
+		return err
+	}
+	fmt.Fprintf(app.out(), "Timer started for %s at %s\n", state.Ticket, state.Started.Format("15:04"))
+	return nil
+}
+
+// StopTimer books the time elapsed since the timer was started, rounded by the configured rule, and
+// clears the timer. The booking is queued for sync when Jira can't be reached.
+func (app *App) StopTimer(ctx context.Context) error {
+	running, err := readTimer()
+	if err != nil {
+		return err
+	}
+	if running == nil {
+		return usageErrorf("no timer is running. use start")
+	}
+
+	if app.Profile == "" {
+		app.Profile = running.Profile
+	}
+	if err := app.loadConf(); err != nil {
+		return err
+	}
+	app.connect()
+
+	var rule = app.Round
+	if rule == "" {
+		rule = app.Configuration.Rounding
+	}
+	rounding, err := parseRoundingRule(rule)
+	if err != nil {
+		return &ConfigError{Msg: err.Error()}
+	}
+
+	var elapsed = time.Since(running.Started)
+	var spent = rounding.apply(elapsed)
+	if spent < time.Minute {
+		return usageErrorf("only %s elapsed since the timer was started for %s, nothing to book",
+			elapsed.Round(time.Second), running.Ticket)
+	}
+
+	if _, err := app.bookTime(ctx, running.Ticket, Duration(spent/time.Second), jiraTimestamp(running.Started), running.Comment); err != nil {
+		return err
+	}
+	return writeTimer(nil)
+}
+
+// TimerStatus prints the running timer.
+func (app *App) TimerStatus() error {
+	running, err := readTimer()
+	if err != nil {
+		return err
+	}
+	if running == nil {
+		fmt.Fprintln(app.out(), "No timer is running")
+		return nil
+	}
+	fmt.Fprintf(app.out(), "%s running for %s since %s", running.Ticket,
+		Duration(time.Since(running.Started).Truncate(time.Minute)/time.Second), running.Started.Format("2006-01-02 15:04"))
+	if running.Comment != "" {
+		fmt.Fprintf(app.out(), " (%s)", running.Comment)
+	}
+	fmt.Fprintln(app.out())
+	return nil
+}
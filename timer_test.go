@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/praveenprem/timesheet/jira"
+)
+
+// bookingAPI records the worklogs added through it, on issues of any key.
+type bookingAPI struct {
+	jira.API
+	booked map[string][]*jira.TimeLog
+}
+
+func (a *bookingAPI) Issue(ctx context.Context, issueKey string) (*jira.SearchIssue, error) {
+	return &jira.SearchIssue{Key: issueKey}, nil
+}
+
+func (a *bookingAPI) AddWorklog(ctx context.Context, issueKey string, slot *jira.TimeLog) (*jira.Worklog, error) {
+	if a.booked == nil {
+		a.booked = make(map[string][]*jira.TimeLog)
+	}
+	a.booked[issueKey] = append(a.booked[issueKey], slot)
+	return &jira.Worklog{Id: "10000"}, nil
+}
+
+// newTimerApp returns an App with its state kept in a temporary directory and a configuration holding the
+// profile work, which rounds timers up to the half hour.
+func newTimerApp(t *testing.T) (*App, *bookingAPI, *bytes.Buffer) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	var config = filepath.Join(t.TempDir(), "config.json")
+	t.Setenv("TIMESHEET_CONFIG", config)
+	t.Setenv("TIMESHEET_PROFILE", "")
+	if err := os.WriteFile(config, []byte(`{"profiles": {"work": {"domain": "example.atlassian.net",
+		"email": "dev@example.com", "token": {"source": "value", "value": "secret"}, "rounding": "up:30"}}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var api = &bookingAPI{}
+	var out bytes.Buffer
+	return &App{Client: api, Out: &out}, api, &out
+}
+
+// backdate moves the start of the running timer back by the given time.
+func backdate(t *testing.T, elapsed time.Duration) {
+	running, err := readTimer()
+	if err != nil || running == nil {
+		t.Fatalf("no timer is running: %v", err)
+	}
+	running.Started = running.Started.Add(-elapsed)
+	if err := writeTimer(running); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTimerStartAndStop(t *testing.T) {
+	var app, api, out = newTimerApp(t)
+	var ctx = context.Background()
+
+	app.Command, app.Ticket, app.Comment, app.Profile = "start", "DDSP-1", "pairing", "work"
+	if err := app.RunTimer(ctx); err != nil {
+		t.Fatal(err)
+	}
+	running, err := readTimer()
+	if err != nil || running == nil || running.Ticket != "DDSP-1" || running.Comment != "pairing" || running.Profile != "work" {
+		t.Fatalf("start saved %+v, %v", running, err)
+	}
+	var usageErr *UsageError
+	if err := app.RunTimer(ctx); !errors.As(err, &usageErr) {
+		t.Errorf("a second start = %v, want it refused", err)
+	}
+
+	app.Command = "status"
+	if err := app.RunTimer(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "DDSP-1 running for 0m since") || !strings.Contains(out.String(), "(pairing)") {
+		t.Errorf("status wrote %q", out.String())
+	}
+
+	// Under a minute is too short to book unless rounded up, and leaves the timer running.
+	app.Command, app.Profile, app.Round = "stop", "", "off"
+	if err := app.RunTimer(ctx); !errors.As(err, &usageErr) {
+		t.Errorf("stopping at once = %v, want it refused", err)
+	}
+	if running, _ := readTimer(); running == nil {
+		t.Fatal("the refused stop cleared the timer")
+	}
+
+	backdate(t, 52*time.Minute)
+	app.Round = ""
+	if err := app.RunTimer(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if booked := api.booked["DDSP-1"]; len(booked) != 1 || booked[0].TimeSpentSeconds != 3600 {
+		t.Fatalf("stop booked %+v, want 52 minutes rounded up to an hour", booked)
+	}
+	if app.Configuration.Profile != "work" {
+		t.Errorf("stop loaded profile %q, want the one the timer was started with", app.Configuration.Profile)
+	}
+	if running, err := readTimer(); running != nil || err != nil {
+		t.Errorf("the timer is still running after stop: %+v, %v", running, err)
+	}
+
+	app.Command = "status"
+	if err := app.RunTimer(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(out.String(), "No timer is running\n") {
+		t.Errorf("status wrote %q", out.String())
+	}
+	if err := app.StopTimer(ctx); !errors.As(err, &usageErr) {
+		t.Errorf("stop without a timer = %v, want a usage error", err)
+	}
+}
+
+func TestTimerSwitch(t *testing.T) {
+	var app, api, _ = newTimerApp(t)
+	var ctx = context.Background()
+
+	app.Command, app.Ticket = "start", "DDSP-2"
+	if err := app.RunTimer(ctx); err != nil {
+		t.Fatal(err)
+	}
+	backdate(t, 20*time.Minute)
+
+	app.Command, app.Ticket, app.Round = "switch", "DDSP-3", "nearest:15"
+	if err := app.RunTimer(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if booked := api.booked["DDSP-2"]; len(booked) != 1 || booked[0].TimeSpentSeconds != 900 {
+		t.Errorf("switch booked %+v, want 20 minutes rounded to the nearest quarter hour", booked)
+	}
+	if running, err := readTimer(); err != nil || running == nil || running.Ticket != "DDSP-3" {
+		t.Errorf("after switch the timer is %+v, %v, want one running for DDSP-3", running, err)
+	}
+}
+
+func TestRoundingRule(t *testing.T) {
+	for _, c := range []struct {
+		rule    string
+		elapsed time.Duration
+		want    time.Duration
+	}{
+		{"", 52*time.Minute + 40*time.Second, 53 * time.Minute},
+		{"off", 7 * time.Minute, 7 * time.Minute},
+		{"nearest:15", 52 * time.Minute, 45 * time.Minute},
+		{"nearest:15", 53 * time.Minute, time.Hour},
+		{"up:30", 31 * time.Minute, time.Hour},
+		{"down:5", 14 * time.Minute, 10 * time.Minute},
+	} {
+		rounding, err := parseRoundingRule(c.rule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := rounding.apply(c.elapsed); got != c.want {
+			t.Errorf("%q rounds %s to %s, want %s", c.rule, c.elapsed, got, c.want)
+		}
+	}
+	for _, rule := range []string{"nearest", "up:0", "up:x", "sideways:15"} {
+		if _, err := parseRoundingRule(rule); err == nil {
+			t.Errorf("parseRoundingRule(%q) gave no error", rule)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/praveenprem/timesheet/jira"
+)
+
+/**
+ * Package name: main
+ * Project name: timesheet
+ * Created on: 18/10/2026 10:31
+ */
+
+// ListWorklogs prints the user's worklogs of the day with their IDs, limited to -r when given.
+func (app *App) ListWorklogs(ctx context.Context) error {
+	day, err := time.Parse(YmdFormat, app.getDate())
+	if err != nil {
+		return err
+	}
+
+	var issues = &jira.SearchResult{}
+	if app.Ticket != "" {
+		issues.Issues = append(issues.Issues, jira.SearchIssue{Key: app.Ticket})
+	} else {
+		issues, err = getIssuesUpdatedBetweenDays(ctx, app.Client, day, day)
+		if err != nil {
+			return err
+		}
+	}
+
+	worklogs, err := app.getWorklogs(ctx, issues, day, day)
+	if err != nil {
+		return err
+	}
+
+	user, err := app.currentUser(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(app.out(), "Worklogs: (%s):\n", app.getDate())
+	fmt.Fprintf(app.out(), "%-10s %-15s %-10s %-8s %s\n", "ID", "Issue", "Started", "Time", "Comment")
+	var found int
+	for _, wLog := range filterByUser(user, worklogs) {
+		for _, log := range wLog.Worklogs {
+			if !app.isDateMatch(log.Started) {
+				continue
+			}
+			fmt.Fprintf(app.out(), "%-10s %-15s %-10s %-8s %s\n", log.Id, wLog.Key, app.startedClock(log.Started),
+				Duration(log.TimeSpentSeconds).Text(app.Configuration.DurationFormat, "%.2fh"), log.Comment.Text())
+			found++
+		}
+	}
+	if found == 0 {
+		fmt.Fprintln(app.out(), "No worklogs found")
+	}
+	return nil
+}
+
+// EditWorklog changes the time spent, start date or comment of the worklog given to the edit command,
+// keeping the fields which weren't given on the command line.
+func (app *App) EditWorklog(ctx context.Context) error {
+	current, err := app.ownWorklog(ctx, app.EditId)
+	if err != nil {
+		return err
+	}
+
+	var slot jira.TimeLog
+	var changes []string
+	if app.TimeSpent != "" {
+		slot.TimeSpentSeconds = app.Spent.Seconds()
+		changes = append(changes, fmt.Sprintf("time spent %s -> %s", Duration(current.TimeSpentSeconds), app.Spent))
+	}
+	if app.isStartSet() {
+		slot.Started = app.Started
+		changes = append(changes, fmt.Sprintf("started %s -> %s", current.Started, app.Started))
+	}
+	if app.isFlagSet("m") {
+		slot.Comment = jira.NewComment(app.Comment)
+		changes = append(changes, fmt.Sprintf("comment %q -> %q", current.Comment.Text(), app.Comment))
+	}
+
+	var question = fmt.Sprintf("Update worklog %s on %s (%s)?", app.EditId, app.Ticket, strings.Join(changes, ", "))
+	if !app.confirm(app.in(), app.out(), question) {
+		fmt.Fprintln(app.out(), "Aborted")
+		return nil
+	}
+
+	updated, err := app.Client.UpdateWorklog(ctx, app.Ticket, app.EditId, &slot, app.estimateAdjustment())
+	if err != nil {
+		return fmt.Errorf("updating worklog %s on %s: %w", app.EditId, app.Ticket, err)
+	}
+	app.rememberWorklog(updated)
+	fmt.Fprintf(app.out(), "Worklog %s on issue %s updated\n", app.EditId, app.Ticket)
+	return nil
+}
+
+// DeleteWorklog removes the worklog given to the delete command.
+func (app *App) DeleteWorklog(ctx context.Context) error {
+	current, err := app.ownWorklog(ctx, app.DeleteId)
+	if err != nil {
+		return err
+	}
+
+	var question = fmt.Sprintf("Delete %s booked to %s on %s (%s)?", Duration(current.TimeSpentSeconds),
+		app.Ticket, current.Started, current.Comment.Text())
+	if !app.confirm(app.in(), app.out(), question) {
+		fmt.Fprintln(app.out(), "Aborted")
+		return nil
+	}
+
+	if err := app.Client.DeleteWorklog(ctx, app.Ticket, app.DeleteId, app.estimateAdjustment()); err != nil {
+		return fmt.Errorf("deleting worklog %s on %s: %w", app.DeleteId, app.Ticket, err)
+	}
+	app.forgetWorklog(app.DeleteId)
+	fmt.Fprintf(app.out(), "Worklog %s deleted from issue %s\n", app.DeleteId, app.Ticket)
+	return nil
+}
+
+// ownWorklog looks up the worklog of the ticket to change, refusing those booked by someone else.
+func (app *App) ownWorklog(ctx context.Context, id string) (*jira.Worklog, error) {
+	current, err := app.Client.Worklog(ctx, app.Ticket, id)
+	if err != nil {
+		return nil, fmt.Errorf("looking up worklog %s on %s: %w", id, app.Ticket, err)
+	}
+	user, err := app.currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !isAuthor(user, *current) {
+		return nil, &TicketError{Msg: fmt.Sprintf("worklog %s on %s was booked by %s, only your own worklogs can be changed",
+			id, app.Ticket, current.Author.DisplayName)}
+	}
+	return current, nil
+}
+
+func (app *App) estimateAdjustment() jira.EstimateAdjustment {
+	return jira.EstimateAdjustment{
+		Mode:        app.Estimate,
+		NewEstimate: app.NewEstimate,
+		IncreaseBy:  app.IncreaseBy,
+	}
+}
+
+// confirm asks a yes/no question, answering yes on its own when -yes is given.
+func (app *App) confirm(in io.Reader, out io.Writer, question string) bool {
+	if app.Yes {
+		return true
+	}
+	fmt.Fprintf(out, "%s [y/N]: ", question)
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	default:
+		return false
+	}
+}
+
+// startedClock returns the time of day of a Jira started timestamp in the profile's timezone.
+func (app *App) startedClock(started string) string {
+	if local, err := app.localTime(started); err == nil {
+		return local.Format("15:04")
+	}
+	if parts := strings.SplitN(started, "T", 2); len(parts) == 2 && len(parts[1]) >= 5 {
+		return parts[1][:5]
+	}
+	return started
+}